@@ -0,0 +1,255 @@
+package i2c
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const ioctlI2cRetries uint = 0x00000701
+
+// Capabilities - functionality supported by the bus adapter (the I2C_FUNC_* bitmask reported by I2C_FUNCS)
+type Capabilities uint32
+
+// Adapter capabilities
+const (
+	CapabilityI2C                 Capabilities = 0x00000001 // Plain I2C transfers (I2C_RDWR, read and write)
+	CapabilityTenBitAddress       Capabilities = 0x00000002
+	CapabilityProtocolMangling    Capabilities = 0x00000004 // MessageIgnoreNak, MessageRevDirAddr and MessageNoReadAck
+	CapabilitySMBusPEC            Capabilities = 0x00000008
+	CapabilityNoStart             Capabilities = 0x00000010 // MessageNoStart
+	CapabilitySlave               Capabilities = 0x00000020
+	CapabilitySMBusBlockProcCall  Capabilities = 0x00008000
+	CapabilitySMBusQuick          Capabilities = 0x00010000
+	CapabilitySMBusReadByte       Capabilities = 0x00020000
+	CapabilitySMBusWriteByte      Capabilities = 0x00040000
+	CapabilitySMBusReadByteData   Capabilities = 0x00080000
+	CapabilitySMBusWriteByteData  Capabilities = 0x00100000
+	CapabilitySMBusReadWordData   Capabilities = 0x00200000
+	CapabilitySMBusWriteWordData  Capabilities = 0x00400000
+	CapabilitySMBusProcCall       Capabilities = 0x00800000
+	CapabilitySMBusReadBlockData  Capabilities = 0x01000000
+	CapabilitySMBusWriteBlockData Capabilities = 0x02000000
+	CapabilitySMBusReadI2CBlock   Capabilities = 0x04000000
+	CapabilitySMBusWriteI2CBlock  Capabilities = 0x08000000
+	CapabilitySMBusHostNotify     Capabilities = 0x10000000
+)
+
+var capabilityNames = []struct {
+	capability Capabilities
+	name       string
+}{
+	{CapabilityI2C, "I2C"},
+	{CapabilityTenBitAddress, "10BitAddress"},
+	{CapabilityProtocolMangling, "ProtocolMangling"},
+	{CapabilitySMBusPEC, "SMBusPEC"},
+	{CapabilityNoStart, "NoStart"},
+	{CapabilitySlave, "Slave"},
+	{CapabilitySMBusBlockProcCall, "SMBusBlockProcCall"},
+	{CapabilitySMBusQuick, "SMBusQuick"},
+	{CapabilitySMBusReadByte, "SMBusReadByte"},
+	{CapabilitySMBusWriteByte, "SMBusWriteByte"},
+	{CapabilitySMBusReadByteData, "SMBusReadByteData"},
+	{CapabilitySMBusWriteByteData, "SMBusWriteByteData"},
+	{CapabilitySMBusReadWordData, "SMBusReadWordData"},
+	{CapabilitySMBusWriteWordData, "SMBusWriteWordData"},
+	{CapabilitySMBusProcCall, "SMBusProcCall"},
+	{CapabilitySMBusReadBlockData, "SMBusReadBlockData"},
+	{CapabilitySMBusWriteBlockData, "SMBusWriteBlockData"},
+	{CapabilitySMBusReadI2CBlock, "SMBusReadI2CBlock"},
+	{CapabilitySMBusWriteI2CBlock, "SMBusWriteI2CBlock"},
+	{CapabilitySMBusHostNotify, "SMBusHostNotify"},
+}
+
+// Has - return true if all the required capabilities are supported
+func (capabilities Capabilities) Has(required Capabilities) bool {
+	return capabilities&required == required
+}
+
+// String - names of the supported capabilities separated by |
+func (capabilities Capabilities) String() string {
+	names := make([]string, 0, len(capabilityNames))
+
+	for _, entry := range capabilityNames {
+		if capabilities.Has(entry.capability) {
+			names = append(names, entry.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "None"
+	}
+
+	return strings.Join(names, "|")
+}
+
+// Get the adapter capabilities
+func (bus *I2Cbus) queryCapabilities() (Capabilities, error) {
+	var funcs uint
+
+	if _, err := bus.ioctl("I2C_FUNCS", ioctlI2cFuncs, unsafe.Pointer(&funcs)); err != nil {
+		return 0, BusError{bus.i2cHandle.Name(), "Get adapter capabilities", err}
+	}
+
+	return Capabilities(funcs), nil
+}
+
+// Capabilities - get the functionality supported by the bus adapter (queried when the bus is opened)
+func (bus *I2Cbus) Capabilities() Capabilities {
+	return bus.capabilities
+}
+
+// SetTimeout - set the adapter timeout (I2C_TIMEOUT). The timeout has 10ms resolution. The timeout is a setting of
+// the adapter, it also applies to other processes and opened buses using the same adapter
+func (bus *I2Cbus) SetTimeout(timeout time.Duration) error {
+	if err := bus.lock(); err != nil {
+		return err
+	}
+	defer bus.unlock()
+
+	if err := bus.setAdapterTimeout(timeout); err != nil {
+		return err
+	}
+
+	bus.adapterTimeout = timeout
+	return nil
+}
+
+// SetRetries - set the number of times the adapter retries a transfer after losing arbitration (I2C_RETRIES)
+func (bus *I2Cbus) SetRetries(retries int) error {
+	if err := bus.lock(); err != nil {
+		return err
+	}
+	defer bus.unlock()
+
+	if err := unix.IoctlSetInt(int(bus.i2cHandle.Fd()), ioctlI2cRetries, retries); err != nil {
+		return BusError{bus.i2cHandle.Name(), "Set adapter retries", &os.PathError{Op: "ioctl I2C_RETRIES", Path: bus.i2cHandle.Name(), Err: err}}
+	}
+
+	return nil
+}
+
+// Fail if a message uses flags that the adapter does not support
+func (bus *I2Cbus) checkMessage(message Message) error {
+	required := Capabilities(0)
+
+	if message.Flags&(MessageIgnoreNak|MessageRevDirAddr|MessageNoReadAck) != 0 {
+		required |= CapabilityProtocolMangling
+	}
+	if message.Flags&MessageNoStart != 0 {
+		required |= CapabilityNoStart
+	}
+	if message.Flags&MessageTenBit != 0 {
+		required |= CapabilityTenBitAddress
+	}
+
+	if err := checkAddress(message.Address, message.Flags&MessageTenBit != 0); err != nil {
+		return err
+	}
+
+	if missing := required &^ bus.capabilities; missing != 0 {
+		return I2CdeviceError{byte(message.Address), fmt.Sprint("Message requires ", missing), ErrNotSupported}
+	}
+
+	return nil
+}
+
+// Perform transfer on adapter that supports only SMBus, by mapping the messages to SMBus commands. The
+// supported message sequences are:
+//
+//   write (no data)                 - SMBus quick write
+//   write (1 byte)                  - SMBus send byte
+//   write (command, 1 byte)         - SMBus write byte data
+//   write (command, 2 bytes)        - SMBus write word data
+//   write (command, up to 32 bytes) - SMBus I2C block write
+//   read (1 byte)                   - SMBus receive byte
+//   write (command), read (1 byte)  - SMBus read byte data
+//   write (command), read (2 bytes) - SMBus read word data
+//   write (command), read (up to 32 bytes) - SMBus I2C block read
+//
+func (bus *I2Cbus) smbusTransfer(messages []Message) error {
+	notSupported := func() error {
+		return I2CdeviceError{byte(messages[0].Address), "Transfer can not be done with SMBus adapter", ErrNotSupported}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	first := messages[0]
+	tenBit := first.Flags&MessageTenBit != 0
+
+	for _, message := range messages {
+		if message.Flags&^(MessageRead|MessageTenBit) != 0 || message.Flags&MessageTenBit != first.Flags&MessageTenBit || message.Address != first.Address {
+			return notSupported()
+		}
+	}
+
+	var data smbusData
+
+	smbus := func(readWrite uint8, command byte, size uint32) error {
+		return bus.smbusCommand(first.Address, tenBit, readWrite, command, size, &data)
+	}
+
+	if len(messages) == 2 {
+		command, read := first.Buffer, messages[1]
+		if first.Flags&MessageRead != 0 || read.Flags&MessageRead == 0 || len(command) != 1 {
+			return notSupported()
+		}
+
+		switch length := len(read.Buffer); {
+		case length == 1:
+			err := smbus(smbusRead, command[0], smbusByteData)
+			read.Buffer[0] = data[0]
+			return err
+		case length == 2:
+			err := smbus(smbusRead, command[0], smbusWordData)
+			read.Buffer[0], read.Buffer[1] = byte(data.word()), byte(data.word()>>8) // SMBus words are sent low byte first
+			return err
+		case length <= SMBusBlockMax:
+			data[0] = byte(length)
+			if err := smbus(smbusRead, command[0], smbusI2cBlockData); err != nil {
+				return err
+			}
+
+			copy(read.Buffer, data.block())
+			return nil
+		}
+
+		return notSupported()
+	} else if len(messages) != 1 {
+		return notSupported()
+	}
+
+	if first.Flags&MessageRead != 0 {
+		if len(first.Buffer) != 1 {
+			return notSupported()
+		}
+
+		err := smbus(smbusRead, 0, smbusByte)
+		first.Buffer[0] = data[0]
+		return err
+	}
+
+	switch length := len(first.Buffer); {
+	case length == 0:
+		return smbus(smbusWrite, 0, smbusQuick)
+	case length == 1:
+		return smbus(smbusWrite, first.Buffer[0], smbusByte)
+	case length == 2:
+		data[0] = first.Buffer[1]
+		return smbus(smbusWrite, first.Buffer[0], smbusByteData)
+	case length == 3:
+		data.setWord(uint16(first.Buffer[1]) | uint16(first.Buffer[2])<<8)
+		return smbus(smbusWrite, first.Buffer[0], smbusWordData)
+	case length <= SMBusBlockMax+1:
+		data.setBlock(first.Buffer[1:])
+		return smbus(smbusWrite, first.Buffer[0], smbusI2cBlockData)
+	}
+
+	return notSupported()
+}
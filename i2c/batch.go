@@ -0,0 +1,243 @@
+package i2c
+
+import (
+	"errors"
+)
+
+// MaxTransferMessages - maximum number of messages in a single I2C_RDWR call (I2C_RDWR_IOCTL_MAX_MSGS)
+const MaxTransferMessages = 42
+
+// ErrBatchAborted - batched operation was not done because an earlier operation of the batch failed
+var ErrBatchAborted = errors.New("batch aborted by earlier failure")
+
+// Batch - queue of register operations on a device, done together by Flush
+//
+// The queued operations are sent as multi-message transfers (a single I2C_RDWR call for up to
+// MaxTransferMessages messages) instead of one system call per operation. All the operations are done inside one
+// bus Tx. If the bus can not do multi-message transfers (such as SMBus only adapters), the operations are done
+// one by one. If the device has a verify policy, written registers are read back in the same transfer
+//
+type Batch struct {
+	device     I2Cdevice
+	operations []batchOperation
+	err        error // Error queuing an operation
+}
+
+type batchOperation struct {
+	register uint16
+	write    bool
+	value    []byte // Data to write, or buffer to read into
+	readBack []byte // Buffer for reading back written value if verified, nil if not
+	messages []Message
+}
+
+// Batch - Get a batch for queuing operations on the device
+func (device I2Cdevice) Batch() *Batch {
+	return &Batch{device: device}
+}
+
+// Len - number of queued operations
+func (batch *Batch) Len() int {
+	return len(batch.operations)
+}
+
+func (batch *Batch) add(register uint16, write bool, value []byte) {
+	address, err := batch.device.registerAddress(register)
+	if err != nil {
+		if batch.err == nil {
+			batch.err = err
+		}
+
+		return
+	}
+
+	operation := batchOperation{register: register, write: write, value: value}
+
+	if write {
+		operation.messages = []Message{batch.device.message(0, append(address, value...))}
+
+		if batch.device.Verify.verifies(register, len(value)) {
+			operation.readBack = make([]byte, len(value))
+			operation.messages = append(operation.messages, batch.device.message(0, address), batch.device.message(MessageRead, operation.readBack))
+		}
+	} else {
+		operation.messages = []Message{batch.device.message(0, address), batch.device.message(MessageRead, value)}
+	}
+
+	batch.operations = append(batch.operations, operation)
+}
+
+// WriteRegister - queue write of value of a given width to a register
+func (batch *Batch) WriteRegister(register uint16, width ValueWidth, value uint32) {
+	if err := batch.device.checkWidth(register, width); err != nil {
+		if batch.err == nil {
+			batch.err = err
+		}
+
+		return
+	}
+
+	buffer := make([]byte, width)
+	batch.device.Codec.encodeValue(buffer, value)
+	batch.add(register, true, buffer)
+}
+
+// WriteByteRegister - queue write of byte value to a register
+func (batch *Batch) WriteByteRegister(register uint16, value byte) {
+	batch.add(register, true, []byte{value})
+}
+
+// WriteRegisters - queue write of data to consecutive registers starting at a given register
+func (batch *Batch) WriteRegisters(register uint16, data []byte) {
+	batch.add(register, true, append([]byte(nil), data...))
+}
+
+// ReadRegisters - queue read of consecutive registers starting at a given register into buffer. The buffer is
+// filled when the batch is flushed
+func (batch *Batch) ReadRegisters(register uint16, buffer []byte) {
+	batch.add(register, false, buffer)
+}
+
+// Split the operations into chunks that fit in a single transfer
+func (batch *Batch) chunks() [][]batchOperation {
+	var chunks [][]batchOperation
+
+	start, count := 0, 0
+	for i, operation := range batch.operations {
+		if count+len(operation.messages) > MaxTransferMessages {
+			chunks = append(chunks, batch.operations[start:i])
+			start, count = i, 0
+		}
+
+		count += len(operation.messages)
+	}
+
+	if start < len(batch.operations) {
+		chunks = append(chunks, batch.operations[start:])
+	}
+
+	return chunks
+}
+
+func (device I2Cdevice) transferChunk(chunk []batchOperation) error {
+	var messages []Message
+
+	write := false
+	for _, operation := range chunk {
+		messages = append(messages, operation.messages...)
+		write = write || operation.write
+	}
+
+	return device.Retry.doContext(device.operationContext(), write, func() error {
+		return device.Bus.Transfer(messages...)
+	})
+}
+
+// Update the cache for a done operation
+func (device I2Cdevice) cacheOperation(operation batchOperation, err error) {
+	if device.Cache == nil {
+		return
+	}
+
+	switch {
+	case operation.write && err == nil:
+		device.Cache.write(operation.register, operation.value, false)
+	case operation.write:
+		device.Cache.forget(operation.register, len(operation.value))
+	case err == nil:
+		device.Cache.store(operation.register, operation.value)
+	}
+}
+
+// Do a single operation without batching
+func (device I2Cdevice) do(operation batchOperation) error {
+	if operation.write {
+		return device.writeRegister(operation.register, operation.value)
+	}
+
+	return device.readRegister(operation.register, operation.value)
+}
+
+// Do the operations of a chunk, setting their results
+func (device I2Cdevice) flushChunk(chunk []batchOperation, results []error) {
+	var err error
+
+	cacheOnly := device.Cache != nil && device.Cache.isCacheOnly()
+	if !cacheOnly {
+		err = device.transferChunk(chunk)
+	}
+
+	if cacheOnly || errors.Is(err, ErrNotSupported) {
+		// Writes go only to the cache, or multi-message transfers are not supported, do the operations one by one
+		for i, operation := range chunk {
+			if results[i] = device.do(operation); results[i] != nil {
+				for j := i + 1; j < len(chunk); j++ {
+					results[j] = ErrBatchAborted
+				}
+
+				return
+			}
+		}
+
+		return
+	}
+
+	for i, operation := range chunk {
+		if err != nil {
+			results[i] = device.registerError(operation.register, err)
+		} else if operation.readBack != nil {
+			results[i] = device.verifyError(operation.register, operation.value, operation.readBack)
+		}
+
+		device.cacheOperation(operation, results[i])
+	}
+}
+
+// Flush - do the queued operations and empty the batch. Returns the result of each operation, and the first error.
+// If a transfer fails, all its operations get its error, and the operations that were not done get
+// ErrBatchAborted
+func (batch *Batch) Flush() ([]error, error) {
+	operations, queueErr := batch.operations, batch.err
+	chunks := batch.chunks()
+	batch.operations, batch.err = nil, nil
+
+	results := make([]error, len(operations))
+	if queueErr != nil {
+		return results, queueErr
+	}
+
+	var firstErr error
+
+	txErr := batch.device.Tx(func(device I2Cdevice) error {
+		index := 0
+
+		for _, chunk := range chunks {
+			chunkResults := results[index : index+len(chunk)]
+			index += len(chunk)
+
+			if firstErr != nil {
+				for i := range chunkResults {
+					chunkResults[i] = ErrBatchAborted
+				}
+
+				continue
+			}
+
+			device.flushChunk(chunk, chunkResults)
+
+			for _, err := range chunkResults {
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if txErr != nil {
+		return results, txErr
+	}
+
+	return results, firstErr
+}
@@ -0,0 +1,226 @@
+package i2c
+
+import (
+	"sync"
+)
+
+// RegisterCache - write-through cache of device registers
+//
+// Registers are cached per byte address, assuming that a multi-byte access covers consecutive registers (as done
+// by the VL6180x and most devices with 8 bit registers). Reads of registers that are all cached are served from
+// memory. Volatile registers (registers changed by the device, or whose writes trigger actions) are never cached.
+//
+// Written values are kept as the device configuration. MarkDirty marks the configuration as not being in the
+// device (for example after the device was reset), and SyncCache writes the dirty registers back in the order
+// they were first written
+//
+type RegisterCache struct {
+	mutex     sync.Mutex
+	entries   map[uint16]*cacheEntry
+	order     []uint16 // Written registers, in the order of their first write
+	cacheOnly bool
+	volatile  func(register uint16) bool
+}
+
+type cacheEntry struct {
+	value   byte
+	written bool // Value is configuration written to the device
+	dirty   bool // Value was not written to the device
+}
+
+// NewRegisterCache - create register cache. volatile returns true for registers that must not be cached, it may
+// be nil if no register is volatile
+func NewRegisterCache(volatile func(register uint16) bool) *RegisterCache {
+	if volatile == nil {
+		volatile = func(uint16) bool { return false }
+	}
+
+	return &RegisterCache{entries: make(map[uint16]*cacheEntry), volatile: volatile}
+}
+
+// WithCache - Get device object that caches its registers in cache
+func (device I2Cdevice) WithCache(cache *RegisterCache) I2Cdevice {
+	device.Cache = cache
+	return device
+}
+
+// SetCacheOnly - when set, register writes only update the cache and mark the registers dirty (use while the
+// device is not accessible). Use SyncCache to write the dirty registers
+func (cache *RegisterCache) SetCacheOnly(cacheOnly bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.cacheOnly = cacheOnly
+}
+
+// MarkDirty - mark all the written registers as dirty, so SyncCache writes the whole configuration
+func (cache *RegisterCache) MarkDirty() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for _, register := range cache.order {
+		cache.entries[register].dirty = true
+	}
+}
+
+// Dirty - get the dirty registers, in the order they were first written
+func (cache *RegisterCache) Dirty() []uint16 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	var dirty []uint16
+
+	for _, register := range cache.order {
+		if cache.entries[register].dirty {
+			dirty = append(dirty, register)
+		}
+	}
+
+	return dirty
+}
+
+// Invalidate - drop all cached values, including dirty ones
+func (cache *RegisterCache) Invalidate() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.entries = make(map[uint16]*cacheEntry)
+	cache.order = nil
+}
+
+// Fill value from the cache, return false if any of the registers is not cached
+func (cache *RegisterCache) read(register uint16, value []byte) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for i := range value {
+		entry, found := cache.entries[register+uint16(i)]
+		if !found {
+			return false
+		}
+
+		value[i] = entry.value
+	}
+
+	return true
+}
+
+// Store values read from the device
+func (cache *RegisterCache) store(register uint16, value []byte) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for i, data := range value {
+		address := register + uint16(i)
+
+		if cache.volatile(address) {
+			continue
+		}
+
+		if entry, found := cache.entries[address]; found {
+			entry.value = data
+		} else {
+			cache.entries[address] = &cacheEntry{value: data}
+		}
+	}
+}
+
+// Store values written to the device (or to be written if dirty)
+func (cache *RegisterCache) write(register uint16, value []byte, dirty bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for i, data := range value {
+		address := register + uint16(i)
+
+		if cache.volatile(address) {
+			continue
+		}
+
+		entry, found := cache.entries[address]
+		if !found {
+			entry = &cacheEntry{}
+			cache.entries[address] = entry
+		}
+
+		if !entry.written {
+			entry.written = true
+			cache.order = append(cache.order, address)
+		}
+
+		entry.value = data
+		entry.dirty = dirty
+	}
+}
+
+// Forget registers whose value is not known, keeping written registers dirty so their configuration is not lost
+func (cache *RegisterCache) forget(register uint16, length int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for i := 0; i < length; i++ {
+		address := register + uint16(i)
+
+		if entry, found := cache.entries[address]; found {
+			if entry.written {
+				entry.dirty = true
+			} else {
+				delete(cache.entries, address)
+			}
+		}
+	}
+}
+
+func (cache *RegisterCache) isCacheOnly() bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.cacheOnly
+}
+
+// Update the cache for a register write, return true if the write should only be done to the cache
+func (cache *RegisterCache) cacheOnlyWrite(register uint16, value []byte) bool {
+	cacheOnly := cache.isCacheOnly()
+	if cacheOnly {
+		cache.write(register, value, true)
+	}
+
+	return cacheOnly
+}
+
+// Get dirty register value
+func (cache *RegisterCache) dirtyValue(register uint16) (byte, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if entry, found := cache.entries[register]; found && entry.dirty {
+		return entry.value, true
+	}
+
+	return 0, false
+}
+
+// SyncCache - write the dirty registers of the device's cache to the device, in the order they were first written
+func (device I2Cdevice) SyncCache() error {
+	cache := device.Cache
+	if cache == nil {
+		return nil
+	}
+
+	device.Cache = nil // Write directly to the device, also when in cache only mode
+
+	for _, register := range cache.Dirty() {
+		value, dirty := cache.dirtyValue(register)
+		if !dirty {
+			continue
+		}
+
+		if err := device.WriteByteRegister(register, value); err != nil {
+			return err
+		}
+
+		cache.write(register, []byte{value}, false)
+	}
+
+	return nil
+}
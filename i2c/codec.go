@@ -0,0 +1,78 @@
+package i2c
+
+// RegisterCodec - encoding of register addresses and register values on the bus
+//
+// The zero value is the VL6180x encoding: 16 bit register addresses and multi-byte values, both sent most
+// significant byte first
+//
+type RegisterCodec struct {
+	Address8     bool // Register addresses are sent as a single byte
+	LittleEndian bool // Multi-byte values are sent least significant byte first
+}
+
+// ValueWidth - width of a register value in bytes
+type ValueWidth int
+
+// Register value widths
+const (
+	Value8  ValueWidth = 1
+	Value16 ValueWidth = 2
+	Value24 ValueWidth = 3
+	Value32 ValueWidth = 4
+)
+
+// Encode register address, return false if the register does not fit in the address width
+func (codec RegisterCodec) encodeAddress(register uint16) ([]byte, bool) {
+	if codec.Address8 {
+		return []byte{byte(register)}, register <= 0xff
+	}
+
+	return []byte{byte((register >> 8) & 0xff), byte(register & 0xff)}, true
+}
+
+// Encode value into buffer, the buffer length is the value width
+func (codec RegisterCodec) encodeValue(buffer []byte, value uint32) {
+	for i := range buffer {
+		shift := uint(8 * i)
+
+		if codec.LittleEndian {
+			buffer[i] = byte(value >> shift)
+		} else {
+			buffer[len(buffer)-1-i] = byte(value >> shift)
+		}
+	}
+}
+
+// Decode value from buffer, the buffer length is the value width
+func (codec RegisterCodec) decodeValue(buffer []byte) uint32 {
+	var value uint32
+
+	for i := range buffer {
+		shift := uint(8 * i)
+
+		if codec.LittleEndian {
+			value |= uint32(buffer[i]) << shift
+		} else {
+			value |= uint32(buffer[len(buffer)-1-i]) << shift
+		}
+	}
+
+	return value
+}
+
+// Decode register address from the start of a write, return false if the data is shorter than the address
+func (codec RegisterCodec) decodeAddress(data []byte) (uint16, int, bool) {
+	if codec.Address8 {
+		if len(data) < 1 {
+			return 0, 0, false
+		}
+
+		return uint16(data[0]), 1, true
+	}
+
+	if len(data) < 2 {
+		return 0, 0, false
+	}
+
+	return (uint16(data[0]) << 8) | uint16(data[1]), 2, true
+}
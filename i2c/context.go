@@ -0,0 +1,376 @@
+package i2c
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const ioctlI2cTimeout uint = 0x00000702
+
+// ContextBus - Bus that can bound its operations by a context
+type ContextBus interface {
+	Bus
+
+	// WithContext - get Bus whose operations fail once ctx is done, and are bounded by ctx deadline
+	WithContext(ctx context.Context) Bus
+}
+
+// WithContext - get Bus whose operations are bounded by ctx. If bus does not implement ContextBus, the operations
+// fail if ctx is done before they start, but are not interrupted once started
+func WithContext(bus Bus, ctx context.Context) Bus {
+	if contextBus, ok := bus.(ContextBus); ok {
+		return contextBus.WithContext(ctx)
+	}
+
+	return checkedBus{bus, ctx}
+}
+
+// Set the adapter timeout (I2C_TIMEOUT) which is in 10ms units
+func (bus *I2Cbus) setAdapterTimeout(timeout time.Duration) error {
+	units := int((timeout + 10*time.Millisecond - 1) / (10 * time.Millisecond))
+	if units < 1 {
+		units = 1
+	}
+
+	if err := unix.IoctlSetInt(int(bus.i2cHandle.Fd()), ioctlI2cTimeout, units); err != nil {
+		return BusError{bus.i2cHandle.Name(), "Set adapter timeout", &os.PathError{Op: "ioctl I2C_TIMEOUT", Path: bus.i2cHandle.Name(), Err: err}}
+	}
+
+	return nil
+}
+
+// WithDeadlineTimeout - set the adapter timeout (I2C_TIMEOUT), and lower it for the duration of operations bounded
+// by a context deadline that is earlier. The timeout is then restored to the value set by this option (or by
+// SetTimeout).
+//
+// I2C_TIMEOUT is a setting of the adapter and not of the open bus. While lowered it also applies to the operations
+// of other processes and other opened buses using the same adapter, and each bounded operation costs two more
+// ioctl calls. Without this option the adapter timeout is never changed by operations bounded by a context
+func WithDeadlineTimeout(timeout time.Duration) Option {
+	return func(bus *I2Cbus) error {
+		if err := bus.SetTimeout(timeout); err != nil {
+			return err
+		}
+
+		bus.deadlineTimeout = true
+		return nil
+	}
+}
+
+// Run operation (bus is locked) bounded by ctx. If deadline timeout is enabled (see WithDeadlineTimeout) and ctx has
+// a deadline that is earlier than the adapter timeout, the adapter timeout is lowered for the operation
+func (bus *I2Cbus) bounded(ctx context.Context, operation func() error) error {
+	if err := ctx.Err(); err != nil {
+		return BusError{bus.i2cHandle.Name(), "Bus operation", err}
+	}
+
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline && bus.deadlineTimeout {
+		if remaining := time.Until(deadline); remaining < bus.adapterTimeout {
+			if err := bus.setAdapterTimeout(remaining); err != nil {
+				return err
+			}
+
+			defer bus.setAdapterTimeout(bus.adapterTimeout)
+		}
+	}
+
+	return operation()
+}
+
+// WithContext - get Bus whose operations are bounded by ctx. The operations fail if ctx is done before they start,
+// and are not retried once ctx is done. If the bus was opened with WithDeadlineTimeout, the ctx deadline is
+// applied as the adapter timeout (I2C_TIMEOUT) while they are done
+func (bus *I2Cbus) WithContext(ctx context.Context) Bus {
+	return i2cBusContext{bus, ctx}
+}
+
+type i2cBusContext struct {
+	bus *I2Cbus
+	ctx context.Context
+}
+
+func (bus i2cBusContext) Read(address byte, buffer []byte) (n int, err error) {
+	err = bus.bus.lockedContext(bus.ctx, false, func() error {
+		return bus.bus.bounded(bus.ctx, func() error {
+			n, err = bus.bus.read(address, buffer)
+			return err
+		})
+	})
+
+	return
+}
+
+func (bus i2cBusContext) Write(address byte, buffer []byte) (n int, err error) {
+	err = bus.bus.lockedContext(bus.ctx, true, func() error {
+		return bus.bus.bounded(bus.ctx, func() error {
+			n, err = bus.bus.write(address, buffer)
+			return err
+		})
+	})
+
+	return
+}
+
+func (bus i2cBusContext) Transfer(messages ...Message) error {
+	return bus.bus.lockedContext(bus.ctx, isWriteTransfer(messages), func() error {
+		return bus.bus.bounded(bus.ctx, func() error {
+			return bus.bus.transfer(messages)
+		})
+	})
+}
+
+func (bus i2cBusContext) Tx(fn func(bus Bus) error) error {
+	return bus.bus.Tx(func(Bus) error {
+		return fn(i2cBusTx{bus.bus}.WithContext(bus.ctx))
+	})
+}
+
+func (bus i2cBusContext) Close() error {
+	return bus.bus.Close()
+}
+
+func (bus i2cBusContext) WithContext(ctx context.Context) Bus {
+	return i2cBusContext{bus.bus, ctx}
+}
+
+// WithContext - get Bus for use inside Tx whose operations are bounded by ctx
+func (tx i2cBusTx) WithContext(ctx context.Context) Bus {
+	return i2cBusTxContext{tx, ctx}
+}
+
+type i2cBusTxContext struct {
+	tx  i2cBusTx
+	ctx context.Context
+}
+
+func (bus i2cBusTxContext) Read(address byte, buffer []byte) (n int, err error) {
+	err = bus.tx.bus.retryPolicy.doContext(bus.ctx, false, func() error {
+		return bus.tx.bus.bounded(bus.ctx, func() error {
+			n, err = bus.tx.bus.read(address, buffer)
+			return err
+		})
+	})
+
+	return
+}
+
+func (bus i2cBusTxContext) Write(address byte, buffer []byte) (n int, err error) {
+	err = bus.tx.bus.retryPolicy.doContext(bus.ctx, true, func() error {
+		return bus.tx.bus.bounded(bus.ctx, func() error {
+			n, err = bus.tx.bus.write(address, buffer)
+			return err
+		})
+	})
+
+	return
+}
+
+func (bus i2cBusTxContext) Transfer(messages ...Message) error {
+	return bus.tx.bus.retryPolicy.doContext(bus.ctx, isWriteTransfer(messages), func() error {
+		return bus.tx.bus.bounded(bus.ctx, func() error {
+			return bus.tx.bus.transfer(messages)
+		})
+	})
+}
+
+func (bus i2cBusTxContext) Tx(fn func(bus Bus) error) error {
+	return fn(bus)
+}
+
+func (bus i2cBusTxContext) Close() error {
+	return errCloseInTx
+}
+
+func (bus i2cBusTxContext) WithContext(ctx context.Context) Bus {
+	return i2cBusTxContext{bus.tx, ctx}
+}
+
+// Bus whose operations fail if the context is done before they start
+type checkedBus struct {
+	bus Bus
+	ctx context.Context
+}
+
+func (bus checkedBus) Read(address byte, buffer []byte) (int, error) {
+	if err := bus.ctx.Err(); err != nil {
+		return 0, I2CdeviceError{address, "Read", err}
+	}
+
+	return bus.bus.Read(address, buffer)
+}
+
+func (bus checkedBus) Write(address byte, buffer []byte) (int, error) {
+	if err := bus.ctx.Err(); err != nil {
+		return 0, I2CdeviceError{address, "Write", err}
+	}
+
+	return bus.bus.Write(address, buffer)
+}
+
+func (bus checkedBus) Transfer(messages ...Message) error {
+	if err := bus.ctx.Err(); err != nil && len(messages) > 0 {
+		return I2CdeviceError{byte(messages[0].Address), "Transfer", err}
+	}
+
+	return bus.bus.Transfer(messages...)
+}
+
+func (bus checkedBus) Tx(fn func(bus Bus) error) error {
+	return bus.bus.Tx(func(tx Bus) error {
+		return fn(checkedBus{tx, bus.ctx})
+	})
+}
+
+func (bus checkedBus) Close() error {
+	return bus.bus.Close()
+}
+
+func (bus checkedBus) WithContext(ctx context.Context) Bus {
+	return checkedBus{bus.bus, ctx}
+}
+
+// WithContext - get Bus whose operations fail if ctx is done before they start
+func (bus *SimBus) WithContext(ctx context.Context) Bus {
+	return checkedBus{bus, ctx}
+}
+
+// WithContext - get Bus for use inside Tx whose operations fail if ctx is done before they start
+func (tx simBusTx) WithContext(ctx context.Context) Bus {
+	return checkedBus{tx, ctx}
+}
+
+// WithContext - get device object whose bus operations are bounded by ctx, and whose register access is not
+// retried once ctx is done. If the bus does not implement ContextBus, bus operations are not bounded by ctx
+func (device I2Cdevice) WithContext(ctx context.Context) I2Cdevice {
+	device.ctx = ctx
+
+	if bus, ok := device.Bus.(ContextBus); ok {
+		device.Bus = bus.WithContext(ctx)
+	}
+
+	return device
+}
+
+// Run device operation bounded by ctx. If the bus implements ContextBus the operation is done with bus bounded by ctx.
+// Otherwise the operation is done in a separate goroutine which is abandoned if ctx is done before the
+// operation ends. The abandoned operation still completes in the background
+func (device I2Cdevice) withContext(ctx context.Context, operation func(device I2Cdevice) error) error {
+	if err := ctx.Err(); err != nil {
+		return I2CdeviceError{device.Address, "Operation not started", err}
+	}
+
+	device.ctx = ctx
+
+	if bus, ok := device.Bus.(ContextBus); ok {
+		device.Bus = bus.WithContext(ctx)
+		return operation(device)
+	}
+
+	if ctx.Done() == nil {
+		return operation(device)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- operation(device)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return I2CdeviceError{device.Address, "Operation abandoned", ctx.Err()}
+	}
+}
+
+// WriteRegisterContext - Write value of a given width to a device's register, bounded by ctx
+func (device I2Cdevice) WriteRegisterContext(ctx context.Context, register uint16, width ValueWidth, value uint32) error {
+	return device.withContext(ctx, func(device I2Cdevice) error {
+		return device.WriteRegister(register, width, value)
+	})
+}
+
+// ReadRegisterContext - Read value of a given width from a device's register, bounded by ctx
+func (device I2Cdevice) ReadRegisterContext(ctx context.Context, register uint16, width ValueWidth) (uint32, error) {
+	result := make(chan uint32, 1) // Abandoned operation may still complete, it must not set variables of the caller
+
+	if err := device.withContext(ctx, func(device I2Cdevice) error {
+		value, err := device.ReadRegister(register, width)
+		if err == nil {
+			result <- value
+		}
+
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return <-result, nil
+}
+
+// WriteByteRegisterContext - Write byte value to a device's register, bounded by ctx
+func (device I2Cdevice) WriteByteRegisterContext(ctx context.Context, register uint16, value byte) error {
+	return device.WriteRegisterContext(ctx, register, Value8, uint32(value))
+}
+
+// WriteWordRegisterContext - Write 16 bit value to a device's register, bounded by ctx
+func (device I2Cdevice) WriteWordRegisterContext(ctx context.Context, register uint16, value uint16) error {
+	return device.WriteRegisterContext(ctx, register, Value16, uint32(value))
+}
+
+// WriteDwordRegisterContext - Write 32 bit value to a device's register, bounded by ctx
+func (device I2Cdevice) WriteDwordRegisterContext(ctx context.Context, register uint16, value uint32) error {
+	return device.WriteRegisterContext(ctx, register, Value32, value)
+}
+
+// WriteRegistersContext - Write data to consecutive registers in one transfer, bounded by ctx
+func (device I2Cdevice) WriteRegistersContext(ctx context.Context, register uint16, data []byte) error {
+	data = append([]byte(nil), data...) // Abandoned operation may still use the data
+
+	return device.withContext(ctx, func(device I2Cdevice) error {
+		return device.WriteRegisters(register, data)
+	})
+}
+
+// ReadByteRegisterContext - Read byte from device's register, bounded by ctx
+func (device I2Cdevice) ReadByteRegisterContext(ctx context.Context, register uint16) (byte, error) {
+	value, err := device.ReadRegisterContext(ctx, register, Value8)
+	return byte(value), err
+}
+
+// ReadWordRegisterContext - Read word (16 bits) from a device's register, bounded by ctx
+func (device I2Cdevice) ReadWordRegisterContext(ctx context.Context, register uint16) (uint16, error) {
+	value, err := device.ReadRegisterContext(ctx, register, Value16)
+	return uint16(value), err
+}
+
+// ReadDwordRegisterContext - Read 32 bit value from a device's register, bounded by ctx
+func (device I2Cdevice) ReadDwordRegisterContext(ctx context.Context, register uint16) (uint32, error) {
+	return device.ReadRegisterContext(ctx, register, Value32)
+}
+
+// ReadRegistersContext - Read consecutive registers into buffer in one transfer, bounded by ctx
+func (device I2Cdevice) ReadRegistersContext(ctx context.Context, register uint16, buffer []byte) error {
+	value := make([]byte, len(buffer)) // Abandoned operation may still fill the buffer
+
+	if err := device.withContext(ctx, func(device I2Cdevice) error {
+		return device.ReadRegisters(register, value)
+	}); err != nil {
+		return err
+	}
+
+	copy(buffer, value)
+	return nil
+}
+
+// Context bounding the device operations, set by WithContext and by the context taking register operations
+func (device I2Cdevice) operationContext() context.Context {
+	if device.ctx == nil {
+		return context.Background()
+	}
+
+	return device.ctx
+}
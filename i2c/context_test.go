@@ -0,0 +1,49 @@
+package i2c
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Bus that does not implement ContextBus, with slow transfers
+type slowBus struct {
+	Bus
+	delay time.Duration
+}
+
+func (bus slowBus) Transfer(messages ...Message) error {
+	time.Sleep(bus.delay)
+	return bus.Bus.Transfer(messages...)
+}
+
+func TestReadRegisterContextAbandoned(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	sim := NewSimBus()
+	registers := NewSimRegisterDevice()
+	registers.SetRegisters(0x0010, 0x12, 0x34)
+	sim.Attach(0x29, registers)
+
+	device := Device(slowBus{sim, delay}, 0x29)
+
+	ctx, cancel := context.WithTimeout(context.Background(), delay/5)
+	defer cancel()
+
+	value, err := device.ReadWordRegisterContext(ctx, 0x0010)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Read returned %#x, %v, expected deadline exceeded", value, err)
+	}
+
+	// Let the abandoned read complete, the race detector reports it if it sets the returned value
+	time.Sleep(2 * delay)
+
+	if value != 0 {
+		t.Errorf("Abandoned read returned %#x", value)
+	}
+
+	if value, err = device.ReadWordRegisterContext(context.Background(), 0x0010); err != nil || value != 0x1234 {
+		t.Errorf("Read returned %#x, %v, expected 0x1234", value, err)
+	}
+}
@@ -0,0 +1,66 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Error classes. Errors returned by the package wrap one of them when the failure cause is known, use
+// errors.Is to check for them. The underlying system error (if any) is also available with errors.Is/errors.As
+var (
+	ErrNoAck          = errors.New("no acknowledge from device")
+	ErrTimeout        = errors.New("bus timeout")
+	ErrBusBusy        = errors.New("bus busy")
+	ErrShortTransfer  = errors.New("short transfer")
+	ErrAdapterMissing = errors.New("I2C adapter not found")
+	ErrNotSupported   = errors.New("not supported by adapter")
+)
+
+// BusError - Error returned from bus function that is not related to a specific device
+type BusError struct {
+	Bus         string // Bus device node
+	Description string
+	Err         error // Underlying error
+}
+
+// Error - return error message
+func (theError BusError) Error() string {
+	return fmt.Sprint("I2C bus ", theError.Bus, ": ", theError.Description, ": ", theError.Err)
+}
+
+// Unwrap - return the underlying error
+func (theError BusError) Unwrap() error {
+	return theError.Err
+}
+
+// Is - check if the error is of a given error class
+func (theError BusError) Is(target error) bool {
+	return target != nil && errorClass(theError.Err) == target
+}
+
+// Map system error to error class, return nil if the error does not map to any class
+func errorClass(err error) error {
+	var errno syscall.Errno
+
+	if !errors.As(err, &errno) {
+		return nil
+	}
+
+	switch errno {
+	case unix.ENXIO, unix.EREMOTEIO:
+		return ErrNoAck
+	case unix.ETIMEDOUT:
+		return ErrTimeout
+	case unix.EAGAIN, unix.EBUSY:
+		return ErrBusBusy
+	case unix.ENOENT, unix.ENODEV:
+		return ErrAdapterMissing
+	case unix.EOPNOTSUPP:
+		return ErrNotSupported
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package i2c
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// FaultKind - kind of fault injected by FaultBus
+type FaultKind int
+
+// Fault kinds
+const (
+	FaultNoAck     FaultKind = iota // The operation fails as if the device did not acknowledge
+	FaultShortRead                  // Only part of the data is read
+	FaultBitFlip                    // A random bit of the data read (or written if nothing is read) is inverted
+	FaultLatency                    // The operation is delayed by Latency
+	FaultBusy                       // The operation fails as if the bus is busy
+)
+
+func (kind FaultKind) String() string {
+	switch kind {
+	case FaultNoAck:
+		return "no acknowledge"
+	case FaultShortRead:
+		return "short read"
+	case FaultBitFlip:
+		return "bit flip"
+	case FaultLatency:
+		return "latency"
+	case FaultBusy:
+		return "bus busy"
+	}
+
+	return "unknown fault"
+}
+
+// FaultRule - when and which fault to inject
+//
+// A rule applies to operations on the listed addresses and registers. The register of an operation is decoded
+// from the start of its first written message using Codec, so a rule that lists registers does not apply to plain
+// reads. Of the operations the rule applies to, the first After are skipped. The fault is then injected with
+// the given probability, at most Count times
+//
+type FaultRule struct {
+	Kind        FaultKind
+	Addresses   []uint16      // Device addresses, nil for any address
+	Registers   []uint16      // Registers, nil for any register
+	Codec       RegisterCodec // Register address encoding used to find the register
+	Probability float64       // Probability of injecting the fault, 0 to always inject it
+	After       int           // Number of operations to skip before injecting the fault
+	Count       int           // Maximum number of times to inject the fault, 0 for no limit
+	Latency     time.Duration // Delay added by FaultLatency
+}
+
+type faultRuleState struct {
+	rule     FaultRule
+	matched  int // Number of operations the rule applied to
+	injected int
+}
+
+type faultState struct {
+	mutex    sync.Mutex
+	random   *rand.Rand
+	rules    []*faultRuleState
+	injected int
+}
+
+// FaultBus - Bus wrapper that injects faults into the operations of the wrapped bus
+type FaultBus struct {
+	bus   Bus
+	state *faultState
+}
+
+// NewFaultBus - get bus injecting faults into the operations of bus according to rules. Random choices are
+// made using a generator seeded with seed, so a run can be repeated
+func NewFaultBus(bus Bus, seed int64, rules ...FaultRule) *FaultBus {
+	faultBus := &FaultBus{bus: bus, state: &faultState{random: rand.New(rand.NewSource(seed))}}
+
+	for _, rule := range rules {
+		faultBus.AddRule(rule)
+	}
+
+	return faultBus
+}
+
+// AddRule - add fault injection rule
+func (bus *FaultBus) AddRule(rule FaultRule) {
+	bus.state.mutex.Lock()
+	defer bus.state.mutex.Unlock()
+
+	bus.state.rules = append(bus.state.rules, &faultRuleState{rule: rule})
+}
+
+// ClearRules - remove all rules, stop injecting faults
+func (bus *FaultBus) ClearRules() {
+	bus.state.mutex.Lock()
+	defer bus.state.mutex.Unlock()
+
+	bus.state.rules = nil
+}
+
+// Injected - number of faults injected so far
+func (bus *FaultBus) Injected() int {
+	bus.state.mutex.Lock()
+	defer bus.state.mutex.Unlock()
+
+	return bus.state.injected
+}
+
+func contains(values []uint16, value uint16) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rule FaultRule) applies(messages []Message) bool {
+	if rule.Addresses != nil && !contains(rule.Addresses, messages[0].Address) {
+		return false
+	}
+
+	if rule.Registers != nil {
+		if messages[0].Flags&MessageRead != 0 {
+			return false
+		}
+
+		register, _, ok := rule.Codec.decodeAddress(messages[0].Buffer)
+		if !ok || !contains(rule.Registers, register) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Select the faults to inject into an operation
+func (state *faultState) faults(messages []Message) []FaultRule {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	var faults []FaultRule
+
+	for _, rule := range state.rules {
+		if !rule.rule.applies(messages) {
+			continue
+		}
+
+		rule.matched++
+		if rule.matched <= rule.rule.After || (rule.rule.Count > 0 && rule.injected >= rule.rule.Count) {
+			continue
+		}
+
+		if rule.rule.Probability > 0 && state.random.Float64() >= rule.rule.Probability {
+			continue
+		}
+
+		rule.injected++
+		state.injected++
+		faults = append(faults, rule.rule)
+	}
+
+	return faults
+}
+
+func (state *faultState) intn(n int) int {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	return state.random.Intn(n)
+}
+
+// Invert a random bit of data
+func (state *faultState) flipBit(data []byte) {
+	if len(data) > 0 {
+		bit := state.intn(8 * len(data))
+		data[bit/8] ^= 1 << uint(bit%8)
+	}
+}
+
+// Get the last message that reads data, return false if no message does
+func lastRead(messages []Message) (Message, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Flags&MessageRead != 0 && len(messages[i].Buffer) > 0 {
+			return messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
+
+// Perform operation with faults injected, return the number of bytes read or written by the first message
+func (bus *FaultBus) inject(operation string, messages []Message, perform func(messages []Message) (int, error)) (int, error) {
+	if len(messages) == 0 {
+		_, err := perform(messages)
+		return 0, err
+	}
+
+	faults := bus.state.faults(messages)
+	address := byte(messages[0].Address)
+
+	readLength := 0
+	for _, message := range messages {
+		if message.Flags&MessageRead != 0 {
+			readLength += len(message.Buffer)
+		}
+	}
+
+	for _, fault := range faults {
+		switch fault.Kind {
+		case FaultLatency:
+			time.Sleep(fault.Latency)
+		case FaultNoAck:
+			return 0, I2CdeviceError{address, operation + " (injected fault)", unix.EREMOTEIO}
+		case FaultBusy:
+			return 0, I2CdeviceError{address, operation + " (injected fault)", unix.EBUSY}
+		case FaultBitFlip:
+			if readLength == 0 {
+				// Corrupt a copy of the written data, the caller's buffer is not changed
+				messages = copyMessages(messages)
+				bus.state.flipBit(messages[len(messages)-1].Buffer)
+			}
+		}
+	}
+
+	n, err := perform(messages)
+	if err != nil {
+		return n, err
+	}
+
+	for _, fault := range faults {
+		switch fault.Kind {
+		case FaultBitFlip:
+			if readLength > 0 {
+				bit := bus.state.intn(8 * readLength)
+				for _, message := range messages {
+					if message.Flags&MessageRead != 0 {
+						if bit < 8*len(message.Buffer) {
+							message.Buffer[bit/8] ^= 1 << uint(bit%8)
+							break
+						}
+
+						bit -= 8 * len(message.Buffer)
+					}
+				}
+			}
+		case FaultShortRead:
+			if last, found := lastRead(messages); found {
+				short := bus.state.intn(len(last.Buffer))
+				for i := short; i < len(last.Buffer); i++ {
+					last.Buffer[i] = 0xff // Data lines are pulled up
+				}
+
+				if operation == "Read" {
+					return short, nil
+				}
+
+				return n, I2CdeviceError{address, fmt.Sprintf("%s - read %d of %d bytes (injected fault)", operation, short, len(last.Buffer)), ErrShortTransfer}
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Read - read from the device at a given address, injecting faults
+func (bus *FaultBus) Read(address byte, buffer []byte) (int, error) {
+	return bus.inject("Read", []Message{{Address: uint16(address), Flags: MessageRead, Buffer: buffer}}, func(messages []Message) (int, error) {
+		return bus.bus.Read(address, messages[0].Buffer)
+	})
+}
+
+// Write - write to the device at a given address, injecting faults
+func (bus *FaultBus) Write(address byte, buffer []byte) (int, error) {
+	return bus.inject("Write", []Message{{Address: uint16(address), Buffer: buffer}}, func(messages []Message) (int, error) {
+		return bus.bus.Write(address, messages[0].Buffer)
+	})
+}
+
+// Transfer - perform messages as one transaction, injecting faults
+func (bus *FaultBus) Transfer(messages ...Message) error {
+	_, err := bus.inject("Transfer", messages, func(messages []Message) (int, error) {
+		return 0, bus.bus.Transfer(messages...)
+	})
+
+	return err
+}
+
+// Tx - run fn with exclusive access to the bus, faults are injected into the operations done by fn
+func (bus *FaultBus) Tx(fn func(bus Bus) error) error {
+	return bus.bus.Tx(func(tx Bus) error {
+		return fn(&FaultBus{bus: tx, state: bus.state})
+	})
+}
+
+// Close - close the underlying bus
+func (bus *FaultBus) Close() error {
+	return bus.bus.Close()
+}
+
+// WithContext - get fault injecting bus whose operations are bounded by ctx
+func (bus *FaultBus) WithContext(ctx context.Context) Bus {
+	if contextBus, ok := bus.bus.(ContextBus); ok {
+		return &FaultBus{bus: contextBus.WithContext(ctx), state: bus.state}
+	}
+
+	return checkedBus{bus, ctx}
+}
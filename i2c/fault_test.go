@@ -0,0 +1,150 @@
+package i2c
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"testing"
+	"time"
+)
+
+const faultDevice = 0x29
+
+func faultSim() *SimBus {
+	sim := NewSimBus()
+	device := NewSimRegisterDevice()
+	device.SetRegisters(0x0010, 0x5a, 0x12, 0x34, 0x56)
+	sim.Attach(faultDevice, device)
+
+	return sim
+}
+
+func TestFaultKinds(t *testing.T) {
+	for _, test := range []struct {
+		kind     FaultKind
+		expected error
+	}{
+		{FaultNoAck, ErrNoAck},
+		{FaultBusy, ErrBusBusy},
+	} {
+		bus := NewFaultBus(faultSim(), 1, FaultRule{Kind: test.kind})
+
+		if _, err := Device(bus, faultDevice).ReadByteRegister(0x0010); !errors.Is(err, test.expected) {
+			t.Errorf("Read with %v fault returned %v", test.kind, err)
+		}
+	}
+
+	bus := NewFaultBus(faultSim(), 1, FaultRule{Kind: FaultLatency, Latency: 20 * time.Millisecond})
+	start := time.Now()
+
+	if value, err := Device(bus, faultDevice).ReadByteRegister(0x0010); err != nil || value != 0x5a {
+		t.Errorf("Read with latency fault returned %#x, %v", value, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Read with latency fault took %v", elapsed)
+	}
+
+	bus = NewFaultBus(faultSim(), 1, FaultRule{Kind: FaultBitFlip})
+
+	if value, err := Device(bus, faultDevice).ReadByteRegister(0x0010); err != nil || bits.OnesCount8(value^0x5a) != 1 {
+		t.Errorf("Read with bit flip fault returned %#x, %v, expected a single bit to differ from 0x5a", value, err)
+	}
+
+	// A bit of the written data (register address or value) is flipped, the caller's buffer is not changed
+	bus.ClearRules()
+	bus.AddRule(FaultRule{Kind: FaultBitFlip, Count: 1})
+	data := []byte{0x00, 0x10, 0xa5}
+
+	if _, err := bus.Write(faultDevice, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte{0x00, 0x10, 0xa5}) {
+		t.Errorf("Write with bit flip fault changed the written buffer to % x", data)
+	}
+
+	if value, err := Device(bus, faultDevice).ReadByteRegister(0x0010); err != nil || value == 0xa5 {
+		t.Errorf("Register written with bit flip fault holds %#x, %v", value, err)
+	}
+}
+
+func TestFaultShortRead(t *testing.T) {
+	bus := NewFaultBus(faultSim(), 1, FaultRule{Kind: FaultShortRead})
+
+	if _, err := bus.Write(faultDevice, []byte{0x00, 0x10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read reports the short count, as read(2) does
+	buffer := make([]byte, 4)
+	n, err := bus.Read(faultDevice, buffer)
+	if err != nil {
+		t.Fatalf("Read with short read fault failed: %v", err)
+	}
+
+	if n >= len(buffer) {
+		t.Fatalf("Read with short read fault read %d bytes", n)
+	}
+
+	if !bytes.Equal(buffer[:n], []byte{0x5a, 0x12, 0x34, 0x56}[:n]) || !bytes.Equal(buffer[n:], bytes.Repeat([]byte{0xff}, len(buffer)-n)) {
+		t.Errorf("Read %d bytes: % x", n, buffer)
+	}
+
+	// Transfer has no count to report, it fails
+	if err := Device(bus, faultDevice).ReadRegisters(0x0010, buffer); !errors.Is(err, ErrShortTransfer) {
+		t.Errorf("Transfer with short read fault returned %v", err)
+	}
+}
+
+func TestFaultAfterCount(t *testing.T) {
+	bus := NewFaultBus(faultSim(), 1, FaultRule{Kind: FaultNoAck, Registers: []uint16{0x0011}, After: 2, Count: 2})
+	device := Device(bus, faultDevice)
+
+	expected := []bool{false, false, true, true, false, false}
+
+	for i, fails := range expected {
+		if _, err := device.ReadByteRegister(0x0010); err != nil {
+			t.Errorf("Read of register not listed by the rule failed: %v", err)
+		}
+
+		if _, err := device.ReadByteRegister(0x0011); (err != nil) != fails {
+			t.Errorf("Read %d returned %v, expected failure: %v", i, err, fails)
+		}
+	}
+
+	if injected := bus.Injected(); injected != 2 {
+		t.Errorf("Injected %d faults, expected 2", injected)
+	}
+}
+
+func TestFaultSeed(t *testing.T) {
+	run := func(seed int64) []bool {
+		device := Device(NewFaultBus(faultSim(), seed, FaultRule{Kind: FaultNoAck, Probability: 0.5}), faultDevice)
+		failed := make([]bool, 64)
+
+		for i := range failed {
+			_, err := device.ReadByteRegister(0x0010)
+			failed[i] = err != nil
+		}
+
+		return failed
+	}
+
+	first, second := run(7), run(7)
+	failures := 0
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Runs with the same seed differ at operation %d", i)
+		}
+
+		if first[i] {
+			failures++
+		}
+	}
+
+	if failures == 0 || failures == len(first) {
+		t.Errorf("%d of %d operations failed with probability 0.5", failures, len(first))
+	}
+}
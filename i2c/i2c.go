@@ -1,32 +1,114 @@
 package i2c
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"sync"
+	"time"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
-const ioctlI2cSlave uint = 0x00000703
+const (
+	ioctlI2cSlave  uint = 0x00000703
+	ioctlI2cTenbit uint = 0x00000704
+)
+
+const noDeviceAddress = 0xffff // lastUsedDeviceAddress value forcing the next operation to select the address
+
+// Address ranges
+const (
+	MaxAddress       = 0x7f  // Largest 7 bit device address
+	MaxTenBitAddress = 0x3ff // Largest 10 bit device address
+)
+
+var errCloseInTx = errors.New("i2c: bus can not be closed inside Tx")
+
+// Bus - I2C bus transport
+//
+// I2Cbus implements Bus on top of a /dev/i2c-N device node. Other implementations can simulate, record,
+// forward or instrument the bus traffic. I2Cdevice and the device drivers access the bus only through this
+// interface, so they work unchanged with any implementation
+//
+type Bus interface {
+	// Read - read len(buffer) bytes from the device at a given address
+	Read(address byte, buffer []byte) (int, error)
+
+	// Write - write buffer to the device at a given address
+	Write(address byte, buffer []byte) (int, error)
+
+	// Transfer - perform a sequence of messages as one transaction (repeated start between the messages)
+	Transfer(messages ...Message) error
+
+	// Tx - run fn with exclusive access to the bus. Operations done through the Bus passed to fn are not
+	// interleaved with operations of other goroutines. Use it for multi-step sequences such as read-modify-write
+	Tx(fn func(bus Bus) error) error
+
+	// Close - release the bus
+	Close() error
+}
+
+// MessageFlags - flags controlling a single message of a transaction
+type MessageFlags uint16
+
+// Message flags (same values as the I2C_M_* flags of linux/i2c.h)
+const (
+	MessageRead       MessageFlags = 0x0001 // Read from the device into Buffer (otherwise Buffer is written)
+	MessageTenBit     MessageFlags = 0x0010 // Address is a 10 bit address
+	MessageRecvLen    MessageFlags = 0x0400 // First byte received is the length of the rest of the message
+	MessageNoReadAck  MessageFlags = 0x0800 // Do not acknowledge received bytes
+	MessageIgnoreNak  MessageFlags = 0x1000 // Treat no acknowledge from the device as acknowledge
+	MessageRevDirAddr MessageFlags = 0x2000 // Invert the read/write bit sent with the address
+	MessageNoStart    MessageFlags = 0x4000 // Do not send (repeated) start and address before this message
+	MessageStop       MessageFlags = 0x8000 // Send stop condition after this message
+)
+
+// Message - one read or write segment of a bus transaction
+type Message struct {
+	Address uint16
+	Flags   MessageFlags
+	Buffer  []byte
+}
 
 // I2Cbus Represent I2C bus
 //
+// I2Cbus is safe for concurrent use. The device address selection and the data transfer of each
+// operation are done while holding the bus mutex
+//
 type I2Cbus struct {
+	mutex                 sync.Mutex // Held for the duration of a bus operation
 	i2cHandle             *os.File
-	lastUsedDeviceAddress byte
+	lastUsedDeviceAddress uint16
+	tenBitMode            bool         // I2C_TENBIT is set, lastUsedDeviceAddress is a 10 bit address
+	processLock           *processLock // Cross process lock, held with the mutex if not nil
+	retryPolicy           *RetryPolicy
+	adapterTimeout        time.Duration // Adapter timeout set by SetTimeout, restored after operations bounded by context deadline
+	deadlineTimeout       bool          // Lower the adapter timeout for operations bounded by context deadline (see WithDeadlineTimeout)
+	capabilities          Capabilities
 }
 
 // I2Cdevice repesent a device on I2C bus
 type I2Cdevice struct {
-	Bus     *I2Cbus
-	Address byte
+	Bus       Bus
+	Address   byte
+	Codec     RegisterCodec  // Register address and value encoding, the zero value fits the VL6180x
+	Retry     *RetryPolicy   // Retry policy for register access, nil for no retries
+	TenBit    bool           // Device uses 10 bit addressing, the device address is Address10
+	Address10 uint16         // 10 bit device address, used if TenBit is set (Address holds its low 8 bits)
+	Cache     *RegisterCache // Register cache, nil for no caching
+	Verify    *VerifyPolicy  // Write verify policy, nil for no verification
+
+	ctx context.Context // Context bounding register access (see WithContext), nil for none
 }
 
 // I2CdeviceError - Error returned from I2C device function
 type I2CdeviceError struct {
 	Address     byte
 	Description string
+	Err         error // Underlying error, nil if there is none
 }
 
 // I2CdeviceRegisterError - Error returned from I2C device function that handle device registers
@@ -37,125 +119,520 @@ type I2CdeviceRegisterError struct {
 
 // Error - return error message
 func (theError I2CdeviceError) Error() string {
-	return fmt.Sprint("I2C device address ", theError.Address, ": ", theError.Description)
+	return fmt.Sprint("I2C device address ", theError.Address, ": ", theError.message())
+}
+
+func (theError I2CdeviceError) message() string {
+	if theError.Err != nil {
+		return fmt.Sprint(theError.Description, ": ", theError.Err)
+	}
+
+	return theError.Description
+}
+
+// Unwrap - return the underlying error
+func (theError I2CdeviceError) Unwrap() error {
+	return theError.Err
+}
+
+// Is - check if the error is of a given error class
+func (theError I2CdeviceError) Is(target error) bool {
+	return target != nil && errorClass(theError.Err) == target
 }
 
 // Error - return error message
 func (theError I2CdeviceRegisterError) Error() string {
-	return fmt.Sprint("I2C device address ", theError.Address, " register ", theError.Register, ": ", theError.Description)
+	return fmt.Sprint("I2C device address ", theError.Address, " register ", theError.Register, ": ", theError.message())
 
 }
 
+// Add device register to an error returned from a register access
+func (device I2Cdevice) registerError(register uint16, err error) error {
+	var registerError I2CdeviceRegisterError
+	var deviceError I2CdeviceError
+
+	if errors.As(err, &registerError) {
+		return err
+	} else if errors.As(err, &deviceError) && deviceError.Address == device.Address {
+		return I2CdeviceRegisterError{deviceError, register}
+	}
+
+	return I2CdeviceRegisterError{I2CdeviceError{Address: device.Address, Description: "Register access", Err: err}, register}
+}
+
 // Open - Open a I2C Bus device
 //
-func Open(unit int) (*I2Cbus, error) {
-	deviceName := fmt.Sprint("/dev/i2c-", unit)
+func Open(unit int, options ...Option) (*I2Cbus, error) {
+	return OpenPath(fmt.Sprint("/dev/i2c-", unit), options...)
+}
 
+// OpenPath - Open a I2C bus given the path of its device node
+func OpenPath(deviceName string, options ...Option) (*I2Cbus, error) {
 	i2cHandle, err := os.OpenFile(deviceName, os.O_RDWR, 0755)
 	if err != nil {
-		log.Fatal(err)
+		return nil, BusError{deviceName, "Open", err}
+	}
+
+	bus := &I2Cbus{i2cHandle: i2cHandle, lastUsedDeviceAddress: noDeviceAddress}
+
+	if bus.capabilities, err = bus.queryCapabilities(); err != nil {
+		i2cHandle.Close()
 		return nil, err
 	}
 
-	return &I2Cbus{i2cHandle, 0xff}, nil
+	for _, option := range options {
+		if err := option(bus); err != nil {
+			bus.Close()
+			return nil, err
+		}
+	}
+
+	return bus, nil
 }
 
 // Close - close the bus, must be called when done with the bus (use defer)
 func (bus *I2Cbus) Close() error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.processLock != nil {
+		bus.processLock.close()
+	}
+
 	return bus.i2cHandle.Close()
 }
 
+// Get exclusive access to the bus for one operation
+func (bus *I2Cbus) lock() error {
+	bus.mutex.Lock()
+
+	if bus.processLock != nil {
+		touched, err := bus.processLock.acquire()
+		if err != nil {
+			bus.mutex.Unlock()
+			return err
+		}
+
+		// Another process may have selected a different device
+		if touched {
+			bus.lastUsedDeviceAddress = noDeviceAddress
+		}
+	}
+
+	return nil
+}
+
+func (bus *I2Cbus) unlock() {
+	if bus.processLock != nil {
+		bus.processLock.release()
+	}
+
+	bus.mutex.Unlock()
+}
+
 func (bus *I2Cbus) setCurrentDeviceAddress(address byte) error {
-	var err error = nil
+	return bus.selectDevice(uint16(address), false)
+}
+
+// Select the device used by read, write and SMBus commands. The 10 bit addressing mode is changed only
+// if it is different from the mode used by the previous device
+func (bus *I2Cbus) selectDevice(address uint16, tenBit bool) error {
+	if tenBit != bus.tenBitMode {
+		var value int
+		if tenBit {
+			value = 1
+		}
+
+		if err := unix.IoctlSetInt(int(bus.i2cHandle.Fd()), ioctlI2cTenbit, value); err != nil {
+			return I2CdeviceError{byte(address), "Set 10 bit addressing", &os.PathError{Op: "ioctl I2C_TENBIT", Path: bus.i2cHandle.Name(), Err: err}}
+		}
+
+		bus.tenBitMode = tenBit
+		bus.lastUsedDeviceAddress = noDeviceAddress
+	}
 
 	// Avoid set device address if it is the same as the previous
 	if address != bus.lastUsedDeviceAddress {
-		err = unix.IoctlSetInt(int(bus.i2cHandle.Fd()), ioctlI2cSlave, int(address))
+		if err := unix.IoctlSetInt(int(bus.i2cHandle.Fd()), ioctlI2cSlave, int(address)); err != nil {
+			bus.lastUsedDeviceAddress = noDeviceAddress
+			return I2CdeviceError{byte(address), "Select device", &os.PathError{Op: "ioctl I2C_SLAVE", Path: bus.i2cHandle.Name(), Err: err}}
+		}
+
 		bus.lastUsedDeviceAddress = address
 	}
-	return err
+
+	return nil
 }
 
-// Device - Get device object for a given device address
-func (bus *I2Cbus) Device(address byte) I2Cdevice {
-	return I2Cdevice{bus, address}
+// Check that address fits in 7 bits, or in 10 bits for 10 bit addressing
+func checkAddress(address uint16, tenBit bool) error {
+	if tenBit && address > MaxTenBitAddress {
+		return I2CdeviceError{byte(address), fmt.Sprintf("10 bit address %#x is larger than %#x", address, MaxTenBitAddress), nil}
+	} else if !tenBit && address > MaxAddress {
+		return I2CdeviceError{byte(address), fmt.Sprintf("Address %#x is larger than %#x", address, MaxAddress), nil}
+	}
+
+	return nil
 }
 
-// WriteByteRegister - Write byte value to a device's register
-func (device I2Cdevice) WriteByteRegister(register uint16, value byte) error {
-	if err := device.Bus.setCurrentDeviceAddress(device.Address); err != nil {
+// Issue ioctl on the bus device node, argument points to the ioctl argument structure
+func (bus *I2Cbus) ioctl(name string, request uint, argument unsafe.Pointer) (int, error) {
+	result, _, errno := unix.Syscall(unix.SYS_IOCTL, bus.i2cHandle.Fd(), uintptr(request), uintptr(argument))
+	if errno != 0 {
+		return 0, &os.PathError{Op: "ioctl " + name, Path: bus.i2cHandle.Name(), Err: errno}
+	}
+
+	return int(result), nil
+}
+
+func (bus *I2Cbus) read(address byte, buffer []byte) (int, error) {
+	if !bus.capabilities.Has(CapabilityI2C) {
+		if err := bus.smbusTransfer([]Message{{Address: uint16(address), Flags: MessageRead, Buffer: buffer}}); err != nil {
+			return 0, err
+		}
+
+		return len(buffer), nil
+	}
+
+	if err := bus.setCurrentDeviceAddress(address); err != nil {
+		return 0, err
+	}
+
+	n, err := bus.i2cHandle.Read(buffer)
+	if err != nil {
+		return n, I2CdeviceError{address, "Read", err}
+	}
+
+	return n, nil
+}
+
+func (bus *I2Cbus) write(address byte, buffer []byte) (int, error) {
+	if !bus.capabilities.Has(CapabilityI2C) {
+		if err := bus.smbusTransfer([]Message{{Address: uint16(address), Buffer: buffer}}); err != nil {
+			return 0, err
+		}
+
+		return len(buffer), nil
+	}
+
+	if err := bus.setCurrentDeviceAddress(address); err != nil {
+		return 0, err
+	}
+
+	n, err := bus.i2cHandle.Write(buffer)
+	if err != nil {
+		return n, I2CdeviceError{address, "Write", err}
+	}
+
+	return n, nil
+}
+
+// SetRetryPolicy - retry failed bus operations according to a policy (nil - no retries). Set the policy
+// before the bus is used by more than one goroutine
+func (bus *I2Cbus) SetRetryPolicy(policy *RetryPolicy) {
+	bus.retryPolicy = policy
+}
+
+// Run locked operation, retrying it according to the bus retry policy. The bus is unlocked between attempts
+func (bus *I2Cbus) locked(write bool, operation func() error) error {
+	return bus.lockedContext(context.Background(), write, operation)
+}
+
+// Run locked operation, retrying it according to the bus retry policy until ctx is done
+func (bus *I2Cbus) lockedContext(ctx context.Context, write bool, operation func() error) error {
+	return bus.retryPolicy.doContext(ctx, write, func() error {
+		if err := bus.lock(); err != nil {
+			return err
+		}
+		defer bus.unlock()
+
+		return operation()
+	})
+}
+
+// Read - read from the device at a given address
+func (bus *I2Cbus) Read(address byte, buffer []byte) (n int, err error) {
+	err = bus.locked(false, func() error {
+		n, err = bus.read(address, buffer)
+		return err
+	})
+
+	return
+}
+
+// Write - write to the device at a given address
+func (bus *I2Cbus) Write(address byte, buffer []byte) (n int, err error) {
+	err = bus.locked(true, func() error {
+		n, err = bus.write(address, buffer)
+		return err
+	})
+
+	return
+}
+
+// Transfer - perform messages as a single combined transaction using the I2C_RDWR ioctl. The messages are
+// separated by repeated start conditions, and the bus is not released until the last message is done
+func (bus *I2Cbus) Transfer(messages ...Message) error {
+	return bus.locked(isWriteTransfer(messages), func() error {
+		return bus.transfer(messages)
+	})
+}
+
+// Tx - run fn while holding the bus mutex (and the cross process lock if enabled)
+func (bus *I2Cbus) Tx(fn func(bus Bus) error) error {
+	if err := bus.lock(); err != nil {
 		return err
 	}
+	defer bus.unlock()
+
+	return fn(i2cBusTx{bus})
+}
+
+// Bus passed to Tx functions, operations are done with the bus mutex already held
+type i2cBusTx struct {
+	bus *I2Cbus
+}
+
+func (tx i2cBusTx) Read(address byte, buffer []byte) (n int, err error) {
+	err = tx.bus.retryPolicy.do(false, func() error {
+		n, err = tx.bus.read(address, buffer)
+		return err
+	})
+
+	return
+}
 
-	buffer := []byte{byte((register >> 8) & 0xff), byte(register & 0xff), byte(value)}
-	if n, err := device.Bus.i2cHandle.Write(buffer); err != nil {
+func (tx i2cBusTx) Write(address byte, buffer []byte) (n int, err error) {
+	err = tx.bus.retryPolicy.do(true, func() error {
+		n, err = tx.bus.write(address, buffer)
 		return err
-	} else if n != 3 {
-		return I2CdeviceRegisterError{I2CdeviceError{device.Address, "Write byte register - write != 3"}, register}
+	})
+
+	return
+}
+
+func (tx i2cBusTx) Transfer(messages ...Message) error {
+	return tx.bus.retryPolicy.do(isWriteTransfer(messages), func() error {
+		return tx.bus.transfer(messages)
+	})
+}
+
+func (tx i2cBusTx) Tx(fn func(bus Bus) error) error {
+	return fn(tx)
+}
+
+func (tx i2cBusTx) Close() error {
+	return errCloseInTx
+}
+
+// Device - Get device object for a given device address
+func (bus *I2Cbus) Device(address byte) I2Cdevice {
+	return Device(bus, address)
+}
+
+// Device - Get device object for a device at a given address on any bus implementation
+func Device(bus Bus, address byte) I2Cdevice {
+	return I2Cdevice{Bus: bus, Address: address}
+}
+
+// Device10 - Get device object for a device at a given 10 bit address. The adapter must support 10 bit
+// addressing (see CapabilityTenBitAddress)
+func Device10(bus Bus, address uint16) (I2Cdevice, error) {
+	if err := checkAddress(address, true); err != nil {
+		return I2Cdevice{}, err
+	}
+
+	return I2Cdevice{Bus: bus, Address: byte(address), TenBit: true, Address10: address}, nil
+}
+
+// Get message to or from the device
+func (device I2Cdevice) message(flags MessageFlags, buffer []byte) Message {
+	if device.TenBit {
+		return Message{Address: device.Address10, Flags: flags | MessageTenBit, Buffer: buffer}
+	}
+
+	return Message{Address: uint16(device.Address), Flags: flags, Buffer: buffer}
+}
+
+// Write buffer to the device. Bus.Write takes only 7 bit addresses, so 10 bit devices are written with Transfer
+func (device I2Cdevice) write(buffer []byte) (int, error) {
+	if device.TenBit {
+		if err := device.Bus.Transfer(device.message(0, buffer)); err != nil {
+			return 0, err
+		}
+
+		return len(buffer), nil
+	}
+
+	return device.Bus.Write(device.Address, buffer)
+}
+
+// Tx - run fn with exclusive access to the device's bus. fn gets a device object whose operations are not
+// interleaved with bus operations of other goroutines
+func (device I2Cdevice) Tx(fn func(device I2Cdevice) error) error {
+	return device.Bus.Tx(func(bus Bus) error {
+		device.Bus = bus
+		return fn(device)
+	})
+}
+
+// WithRetryPolicy - Get device object that retries failed register access according to a policy
+func (device I2Cdevice) WithRetryPolicy(policy *RetryPolicy) I2Cdevice {
+	device.Retry = policy
+	return device
+}
+
+// WithCodec - Get device object that encodes register addresses and values using a given codec
+func (device I2Cdevice) WithCodec(codec RegisterCodec) I2Cdevice {
+	device.Codec = codec
+	return device
+}
+
+func (device I2Cdevice) registerAddress(register uint16) ([]byte, error) {
+	if address, ok := device.Codec.encodeAddress(register); ok {
+		return address, nil
+	}
+
+	return nil, I2CdeviceRegisterError{I2CdeviceError{device.Address, "Register address does not fit in 8 bits", nil}, register}
+}
+
+func (device I2Cdevice) checkWidth(register uint16, width ValueWidth) error {
+	if width < Value8 || width > Value32 {
+		return I2CdeviceRegisterError{I2CdeviceError{device.Address, fmt.Sprintf("Invalid register width %d (not between 1...4)", width), nil}, register}
 	}
 
 	return nil
 }
 
-// WriteWordRegister - Write 16 bit value to a device's register
-func (device I2Cdevice) WriteWordRegister(register uint16, value uint16) error {
-	if err := device.Bus.setCurrentDeviceAddress(device.Address); err != nil {
+// Write register address followed by the value bytes
+func (device I2Cdevice) writeRegister(register uint16, value []byte) error {
+	address, err := device.registerAddress(register)
+	if err != nil {
 		return err
 	}
 
-	buffer := []byte{byte((register >> 8) & 0xff), byte(register & 0xff), byte((value >> 8) & 0xff), byte(value)}
-	if n, err := device.Bus.i2cHandle.Write(buffer); err != nil {
-		return err
-	} else if n != 4 {
-		return I2CdeviceRegisterError{I2CdeviceError{device.Address, "Write word register - write != 4"}, register}
+	if device.Cache != nil && device.Cache.cacheOnlyWrite(register, value) {
+		return nil
 	}
 
-	return nil
+	buffer := append(address, value...)
+	err = device.Retry.doContext(device.operationContext(), true, func() error {
+		if n, err := device.write(buffer); err != nil {
+			return device.registerError(register, err)
+		} else if n != len(buffer) {
+			return I2CdeviceRegisterError{I2CdeviceError{device.Address, fmt.Sprintf("Write register - wrote %d of %d bytes", n, len(buffer)), ErrShortTransfer}, register}
+		}
+
+		return nil
+	})
+
+	if err == nil {
+		err = device.verifyWrite(register, value)
+	}
+
+	if device.Cache != nil {
+		if err == nil {
+			device.Cache.write(register, value, false)
+		} else {
+			device.Cache.forget(register, len(value))
+		}
+	}
+
+	return err
 }
 
-// ReadByteRegister - Read byte from device's register
-func (device I2Cdevice) ReadByteRegister(register uint16) (byte, error) {
-	if err := device.Bus.setCurrentDeviceAddress(device.Address); err != nil {
-		return 0, err
+// Write register address and read the register value as a single combined transaction
+func (device I2Cdevice) readRegister(register uint16, value []byte) error {
+	address, err := device.registerAddress(register)
+	if err != nil {
+		return err
 	}
 
-	buffer := []byte{byte((register >> 8) & 0xff), byte(register & 0xff)}
-	if n, err := device.Bus.i2cHandle.Write(buffer); err != nil {
-		return 0, err
-	} else if n != 2 {
-		return 0, I2CdeviceRegisterError{I2CdeviceError{device.Address, "Read byte register - write != 2"}, register}
+	if device.Cache != nil && device.Cache.read(register, value) {
+		return nil
 	}
 
-	value := make([]byte, 1)
-	if n, err := device.Bus.i2cHandle.Read(value); err != nil {
-		return 0, err
-	} else if n != 1 {
-		return 0, I2CdeviceError{device.Address, "ReadByteRegister - did not get 1 byte as response"}
+	err = device.Retry.doContext(device.operationContext(), false, func() error {
+		if err := device.Bus.Transfer(
+			device.message(0, address),
+			device.message(MessageRead, value),
+		); err != nil {
+			return device.registerError(register, err)
+		}
+
+		return nil
+	})
+
+	if device.Cache != nil && err == nil {
+		device.Cache.store(register, value)
 	}
 
-	return value[0], nil
+	return err
 }
 
-// ReadWordRegister - Read word (16 bits) from a device's register
-func (device I2Cdevice) ReadWordRegister(register uint16) (uint16, error) {
-	if err := device.Bus.setCurrentDeviceAddress(device.Address); err != nil {
-		return 0, err
+// WriteRegister - Write value of a given width to a device's register
+func (device I2Cdevice) WriteRegister(register uint16, width ValueWidth, value uint32) error {
+	if err := device.checkWidth(register, width); err != nil {
+		return err
 	}
 
-	buffer := []byte{byte((register >> 8) & 0xff), byte(register & 0xff)}
-	if n, err := device.Bus.i2cHandle.Write(buffer); err != nil {
+	buffer := make([]byte, width)
+	device.Codec.encodeValue(buffer, value)
+	return device.writeRegister(register, buffer)
+}
+
+// ReadRegister - Read value of a given width from a device's register
+func (device I2Cdevice) ReadRegister(register uint16, width ValueWidth) (uint32, error) {
+	if err := device.checkWidth(register, width); err != nil {
 		return 0, err
-	} else if n != 2 {
-		return 0, I2CdeviceRegisterError{I2CdeviceError{device.Address, "Read word register - write != 2"}, register}
 	}
 
-	value := make([]byte, 2)
-	if n, err := device.Bus.i2cHandle.Read(value); err != nil {
+	buffer := make([]byte, width)
+	if err := device.readRegister(register, buffer); err != nil {
 		return 0, err
-	} else if n != 2 {
-		return 0, I2CdeviceError{device.Address, "ReadWordRegister - did not get 2 bytes as response"}
 	}
 
-	return (uint16(value[0]) << 8) | uint16(value[1]), nil
+	return device.Codec.decodeValue(buffer), nil
+}
+
+// WriteByteRegister - Write byte value to a device's register
+func (device I2Cdevice) WriteByteRegister(register uint16, value byte) error {
+	return device.WriteRegister(register, Value8, uint32(value))
+}
+
+// WriteWordRegister - Write 16 bit value to a device's register
+func (device I2Cdevice) WriteWordRegister(register uint16, value uint16) error {
+	return device.WriteRegister(register, Value16, uint32(value))
+}
+
+// WriteDwordRegister - Write 32 bit value to a device's register
+func (device I2Cdevice) WriteDwordRegister(register uint16, value uint32) error {
+	return device.WriteRegister(register, Value32, value)
+}
+
+// WriteRegisters - Write data to consecutive registers starting at a given register in one transfer
+func (device I2Cdevice) WriteRegisters(register uint16, data []byte) error {
+	return device.writeRegister(register, data)
+}
+
+// ReadByteRegister - Read byte from device's register
+func (device I2Cdevice) ReadByteRegister(register uint16) (byte, error) {
+	value, err := device.ReadRegister(register, Value8)
+	return byte(value), err
+}
+
+// ReadWordRegister - Read word (16 bits) from a device's register
+func (device I2Cdevice) ReadWordRegister(register uint16) (uint16, error) {
+	value, err := device.ReadRegister(register, Value16)
+	return uint16(value), err
+}
+
+// ReadDwordRegister - Read 32 bit value from a device's register
+func (device I2Cdevice) ReadDwordRegister(register uint16) (uint32, error) {
+	return device.ReadRegister(register, Value32)
+}
 
+// ReadRegisters - Read consecutive registers starting at a given register into buffer in one transfer
+func (device I2Cdevice) ReadRegisters(register uint16, buffer []byte) error {
+	return device.readRegister(register, buffer)
 }
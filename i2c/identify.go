@@ -0,0 +1,153 @@
+package i2c
+
+import (
+	"sort"
+	"sync"
+)
+
+// Confidence - how sure a probe is that a device is handled by its driver
+type Confidence int
+
+// Confidence levels
+const (
+	ConfidenceNone   Confidence = iota // Not the driver's device
+	ConfidenceLow                      // Device behaves like the driver's device, but can not be identified
+	ConfidenceMedium                   // Identification register matches, but is not unique
+	ConfidenceHigh                     // Device identified by identification registers
+)
+
+func (confidence Confidence) String() string {
+	switch confidence {
+	case ConfidenceNone:
+		return "none"
+	case ConfidenceLow:
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	}
+
+	return "unknown"
+}
+
+// ProbeFunc - check if a device is handled by a driver. Probe functions must not change the device state, and
+// should access only registers that are safe to read on other devices that may be found at the same address.
+// Reading a register writes the register address, which other devices may take as a register write
+type ProbeFunc func(device I2Cdevice) (Confidence, error)
+
+// Prober - a driver's device probe
+type Prober struct {
+	Driver string // Driver name reported in matches
+
+	// Addresses the device can have. If nil the device can be at any address, but it is not probed at
+	// addresses of devices that can be corrupted by the probe writes (see IsWriteSensitiveAddress)
+	Addresses []byte
+
+	Probe ProbeFunc // Called for each candidate address acknowledged by a device
+}
+
+// Match - device identified at a given address
+type Match struct {
+	Address    byte
+	Driver     string // Empty if no driver identified the device
+	Confidence Confidence
+}
+
+var (
+	probersMutex sync.Mutex
+	probers      []Prober
+)
+
+// RegisterProbe - add a driver's probe to the identification registry. Drivers usually register their probe in
+// their package init function
+func RegisterProbe(prober Prober) {
+	probersMutex.Lock()
+	defer probersMutex.Unlock()
+
+	probers = append(probers, prober)
+}
+
+// AddProbeAddresses - add addresses a registered driver's device can have. Use for devices whose address is
+// configured by the application. Probers that can be at any address (nil Addresses) are not changed
+func AddProbeAddresses(driver string, addresses ...byte) {
+	probersMutex.Lock()
+	defer probersMutex.Unlock()
+
+	for i, prober := range probers {
+		if prober.Driver != driver || prober.Addresses == nil {
+			continue
+		}
+
+		updated := append([]byte(nil), prober.Addresses...) // Probers returned by registeredProbers keep the old slice
+		for _, address := range addresses {
+			if !prober.candidate(address) {
+				updated = append(updated, address)
+			}
+		}
+
+		probers[i].Addresses = updated
+	}
+}
+
+func registeredProbers() []Prober {
+	probersMutex.Lock()
+	defer probersMutex.Unlock()
+
+	return append([]Prober(nil), probers...)
+}
+
+func (prober Prober) candidate(address byte) bool {
+	if prober.Addresses == nil {
+		return !IsWriteSensitiveAddress(address)
+	}
+
+	for _, candidate := range prober.Addresses {
+		if candidate == address {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Identify - scan the bus and identify the devices that acknowledged their address
+func Identify(bus Bus) ([]Match, error) {
+	addresses, err := Scan(bus, ScanOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return IdentifyAddresses(bus, addresses), nil
+}
+
+// IdentifyAddresses - run the registered probes on given addresses. Each address has a match for every driver
+// that recognized the device, ordered from the highest confidence. Addresses that no driver recognized have a
+// single match with no driver. A probe that fails is taken as not recognizing the device
+func IdentifyAddresses(bus Bus, addresses []byte) []Match {
+	probers := registeredProbers()
+	matches := make([]Match, 0, len(addresses))
+
+	for _, address := range addresses {
+		found := make([]Match, 0, 1)
+
+		for _, prober := range probers {
+			if !prober.candidate(address) {
+				continue
+			}
+
+			if confidence, err := prober.Probe(Device(bus, address)); err == nil && confidence > ConfidenceNone {
+				found = append(found, Match{Address: address, Driver: prober.Driver, Confidence: confidence})
+			}
+		}
+
+		if len(found) == 0 {
+			found = append(found, Match{Address: address})
+		}
+
+		sort.SliceStable(found, func(i, j int) bool { return found[i].Confidence > found[j].Confidence })
+		matches = append(matches, found...)
+	}
+
+	return matches
+}
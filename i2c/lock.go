@@ -0,0 +1,83 @@
+package i2c
+
+import (
+	"encoding/binary"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Option - option applied when opening a bus
+type Option func(bus *I2Cbus) error
+
+// processLock - advisory lock (flock) shared by all processes using the same bus
+type processLock struct {
+	file      *os.File
+	ownsFile  bool   // file is a lock file opened by the lock (and not the bus device node)
+	lastStamp []byte // stamp written to the lock file when the lock was last acquired by this process
+	counter   uint64
+}
+
+// WithProcessLock - hold an exclusive advisory lock (flock) on the bus device node for the duration of each bus
+// operation, so operations of processes using this option are not interleaved. Since another process may
+// have used the bus between operations, the device address is selected again on every operation
+func WithProcessLock() Option {
+	return func(bus *I2Cbus) error {
+		bus.processLock = &processLock{file: bus.i2cHandle}
+		return nil
+	}
+}
+
+// WithLockFile - like WithProcessLock, but lock a given file (created if needed) instead of the device node.
+// Each process records itself in the lock file when it gets the lock, so the device address is selected
+// again only if another process used the bus since the last operation
+func WithLockFile(path string) Option {
+	return func(bus *I2Cbus) error {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+		if err != nil {
+			return BusError{bus.i2cHandle.Name(), "Open lock file", err}
+		}
+
+		bus.processLock = &processLock{file: file, ownsFile: true}
+		return nil
+	}
+}
+
+// Acquire the lock, return true if another process may have used the bus since the lock was last held
+func (lock *processLock) acquire() (bool, error) {
+	if err := unix.Flock(int(lock.file.Fd()), unix.LOCK_EX); err != nil {
+		return true, BusError{lock.file.Name(), "Lock bus", &os.PathError{Op: "flock", Path: lock.file.Name(), Err: err}}
+	}
+
+	if !lock.ownsFile {
+		return true, nil
+	}
+
+	stamp := make([]byte, 16)
+	n, _ := lock.file.ReadAt(stamp, 0)
+	touched := lock.lastStamp == nil || n != len(stamp) || string(stamp) != string(lock.lastStamp)
+
+	lock.counter++
+	binary.LittleEndian.PutUint64(stamp[0:], uint64(os.Getpid()))
+	binary.LittleEndian.PutUint64(stamp[8:], lock.counter)
+
+	if _, err := lock.file.WriteAt(stamp, 0); err != nil {
+		lock.release()
+		return true, BusError{lock.file.Name(), "Update lock file", err}
+	}
+
+	lock.lastStamp = stamp
+	return touched, nil
+}
+
+func (lock *processLock) release() {
+	unix.Flock(int(lock.file.Fd()), unix.LOCK_UN)
+}
+
+func (lock *processLock) close() error {
+	if lock.ownsFile {
+		return lock.file.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package i2c
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const ioctlI2cRdwr uint = 0x00000707
+
+// i2c_msg - see linux/i2c.h
+type i2cMsg struct {
+	addr  uint16
+	flags uint16
+	len   uint16
+	buf   *byte
+}
+
+// i2c_rdwr_ioctl_data - see linux/i2c-dev.h
+type i2cRdwrIoctlData struct {
+	msgs  *i2cMsg
+	nmsgs uint32
+}
+
+func (bus *I2Cbus) transfer(messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	for _, message := range messages {
+		if err := bus.checkMessage(message); err != nil {
+			return err
+		}
+	}
+
+	if !bus.capabilities.Has(CapabilityI2C) {
+		return bus.smbusTransfer(messages)
+	}
+
+	kernelMessages := make([]i2cMsg, len(messages))
+
+	for i, message := range messages {
+		if len(message.Buffer) > 0xffff {
+			return I2CdeviceError{byte(message.Address), fmt.Sprintf("Transfer - message of %d bytes is too long", len(message.Buffer)), nil}
+		}
+
+		kernelMessages[i] = i2cMsg{addr: message.Address, flags: uint16(message.Flags), len: uint16(len(message.Buffer))}
+		if len(message.Buffer) > 0 {
+			kernelMessages[i].buf = &message.Buffer[0]
+		}
+	}
+
+	data := i2cRdwrIoctlData{msgs: &kernelMessages[0], nmsgs: uint32(len(kernelMessages))}
+
+	if n, err := bus.ioctl("I2C_RDWR", ioctlI2cRdwr, unsafe.Pointer(&data)); err != nil {
+		return I2CdeviceError{byte(messages[0].Address), "Transfer", err}
+	} else if n != len(messages) {
+		return I2CdeviceError{byte(messages[0].Address), fmt.Sprintf("Transfer - %d of %d messages transferred", n, len(messages)), ErrShortTransfer}
+	}
+
+	return nil
+}
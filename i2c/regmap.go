@@ -0,0 +1,175 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRegisterAccess - register does not allow the access (write to read only register or read of write only register)
+var ErrRegisterAccess = errors.New("register access not allowed")
+
+// Access - access mode of a register
+type Access int
+
+// Register access modes
+const (
+	ReadWrite Access = iota
+	ReadOnly
+	WriteOnly
+)
+
+// Register - register definition
+type Register struct {
+	Name    string
+	Address uint16
+	Width   ValueWidth // Value width, 0 is the same as Value8
+	Access  Access
+	Reset   uint32 // Value after power up or reset
+}
+
+// Field - bit field of a register
+type Field struct {
+	Register Register
+	Name     string
+	Shift    uint // Position of the field's least significant bit
+	Bits     uint // Field width in bits
+}
+
+// RegisterMap - register definitions of a device
+type RegisterMap []Register
+
+func (register Register) width() ValueWidth {
+	if register.Width == 0 {
+		return Value8
+	}
+
+	return register.Width
+}
+
+// Mask - mask of all the register bits
+func (register Register) Mask() uint32 {
+	return uint32(1)<<(8*uint(register.width())) - 1
+}
+
+// Mask - mask of the field bits in the register value
+func (field Field) Mask() uint32 {
+	return (uint32(1)<<field.Bits - 1) << field.Shift
+}
+
+// Value - get the register bits holding a given field value (the field value is truncated to the field width).
+// Use to compose register values from fields
+func (field Field) Value(value uint32) uint32 {
+	return (value << field.Shift) & field.Mask()
+}
+
+// Lookup - find register definition by register address
+func (registers RegisterMap) Lookup(address uint16) (Register, bool) {
+	for _, register := range registers {
+		if register.Address == address {
+			return register, true
+		}
+	}
+
+	return Register{}, false
+}
+
+// Names - get the register names, for annotating traces
+func (registers RegisterMap) Names() RegisterNames {
+	names := make(RegisterNames, len(registers))
+
+	for _, register := range registers {
+		names[register.Address] = register.Name
+	}
+
+	return names
+}
+
+func (device I2Cdevice) accessError(register Register, description string) error {
+	return I2CdeviceRegisterError{I2CdeviceError{device.Address, fmt.Sprintf("%s %s", register.Name, description), ErrRegisterAccess}, register.Address}
+}
+
+func (device I2Cdevice) checkAccess(register Register, write bool) error {
+	if write && register.Access == ReadOnly {
+		return device.accessError(register, "is read only")
+	} else if !write && register.Access == WriteOnly {
+		return device.accessError(register, "is write only")
+	}
+
+	return nil
+}
+
+// Get - read register value
+func (device I2Cdevice) Get(register Register) (uint32, error) {
+	if err := device.checkAccess(register, false); err != nil {
+		return 0, err
+	}
+
+	return device.ReadRegister(register.Address, register.width())
+}
+
+// Set - write register value. Fails if the value does not fit in the register width
+func (device I2Cdevice) Set(register Register, value uint32) error {
+	if err := device.checkAccess(register, true); err != nil {
+		return err
+	}
+
+	if value&^register.Mask() != 0 {
+		return I2CdeviceRegisterError{I2CdeviceError{device.Address, fmt.Sprintf("Value %#x does not fit in %s", value, register.Name), nil}, register.Address}
+	}
+
+	return device.WriteRegister(register.Address, register.width(), value)
+}
+
+// UpdateBits - set the register bits in mask to the bits in value, leaving the other bits unchanged. The register
+// is read and written in a single bus Tx. It is not written if its value does not change
+func (device I2Cdevice) UpdateBits(register Register, mask uint32, value uint32) error {
+	if register.Access != ReadWrite {
+		return device.accessError(register, "can not be updated")
+	}
+
+	return device.Tx(func(device I2Cdevice) error {
+		current, err := device.Get(register)
+		if err != nil {
+			return err
+		}
+
+		updated := (current &^ mask) | (value & mask)
+		if updated == current {
+			return nil
+		}
+
+		return device.Set(register, updated)
+	})
+}
+
+// GetField - read field value
+func (device I2Cdevice) GetField(field Field) (uint32, error) {
+	value, err := device.Get(field.Register)
+	return (value & field.Mask()) >> field.Shift, err
+}
+
+// SetField - set field value, leaving the other register fields unchanged. Fails if the value does not fit in
+// the field
+func (device I2Cdevice) SetField(field Field, value uint32) error {
+	if value > field.Mask()>>field.Shift {
+		return I2CdeviceRegisterError{I2CdeviceError{device.Address, fmt.Sprintf("Value %#x does not fit in %s.%s", value, field.Register.Name, field.Name), nil}, field.Register.Address}
+	}
+
+	return device.UpdateBits(field.Register, field.Mask(), field.Value(value))
+}
+
+// GetFlag - read single bit field
+func (device I2Cdevice) GetFlag(field Field) (bool, error) {
+	value, err := device.GetField(field)
+	return value != 0, err
+}
+
+// SetFlag - set single bit field
+func (device I2Cdevice) SetFlag(field Field, set bool) error {
+	var value uint32
+	if set {
+		value = 1
+	}
+
+	return device.SetField(field, value)
+}
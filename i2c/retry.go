@@ -0,0 +1,114 @@
+package i2c
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy - how operations that failed because of transient errors are retried
+//
+// A policy can be attached to an I2Cbus (SetRetryPolicy) or to an I2Cdevice (WithRetryPolicy). Attach it to
+// one of them, a device policy on top of a bus policy multiplies the number of attempts. A policy can be shared
+// by several buses and devices, its counters are updated atomically
+//
+type RetryPolicy struct {
+	retries  uint64 // First in the struct for 64 bit atomic access on 32 bit platforms
+	failures uint64
+
+	Attempts   int           // Total number of attempts, values below 1 mean a single attempt
+	Backoff    time.Duration // Delay before the first retry
+	MaxBackoff time.Duration // The delay is doubled after each retry up to MaxBackoff (0 - no limit)
+
+	// Retryable - return true if an operation that failed with err should be retried. If nil, DefaultRetryable is used
+	Retryable func(err error) bool
+
+	// RetryWrites - retry operations that write to the device. Set only if repeating a partially done write
+	// is harmless for the devices the policy is used with
+	RetryWrites bool
+}
+
+// DefaultRetryable - retry on no acknowledge, timeout, bus busy and short transfer errors. Operations that failed
+// because their context is done are not retried
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return errors.Is(err, ErrNoAck) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrBusBusy) || errors.Is(err, ErrShortTransfer)
+}
+
+// Retries - number of retries done so far
+func (policy *RetryPolicy) Retries() uint64 {
+	return atomic.LoadUint64(&policy.retries)
+}
+
+// Failures - number of operations that failed after all attempts
+func (policy *RetryPolicy) Failures() uint64 {
+	return atomic.LoadUint64(&policy.failures)
+}
+
+// Run operation, retrying it according to the policy. write is true if the operation writes to the device.
+// A nil policy runs the operation once
+func (policy *RetryPolicy) do(write bool, operation func() error) error {
+	return policy.doContext(context.Background(), write, operation)
+}
+
+// Run operation, retrying it according to the policy until ctx is done. The wait between attempts ends when
+// ctx is done, and the error of the last attempt is returned
+func (policy *RetryPolicy) doContext(ctx context.Context, write bool, operation func() error) error {
+	if policy == nil {
+		return operation()
+	}
+
+	attempts := policy.Attempts
+	if write && !policy.RetryWrites {
+		attempts = 1 // Done once, but still counted as failure if it fails
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+
+	backoff := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
+
+		if err == nil {
+			return nil
+		} else if attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			atomic.AddUint64(&policy.failures, 1)
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			atomic.AddUint64(&policy.failures, 1)
+			return err
+		}
+
+		atomic.AddUint64(&policy.retries, 1)
+
+		if backoff *= 2; policy.MaxBackoff != 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// Check if all messages of a transfer are writes. A transfer that includes a read (such as register read)
+// does not change the device state beside setting its register pointer
+func isWriteTransfer(messages []Message) bool {
+	for _, message := range messages {
+		if message.Flags&MessageRead != 0 {
+			return false
+		}
+	}
+
+	return true
+}
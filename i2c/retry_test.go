@@ -0,0 +1,81 @@
+package i2c
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const retryDevice = 0x29
+
+func retryBus(rules ...FaultRule) *FaultBus {
+	sim := NewSimBus()
+	sim.Attach(retryDevice, NewSimRegisterDevice())
+
+	return NewFaultBus(sim, 1, rules...)
+}
+
+func TestRetryCounters(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 3}
+	bus := retryBus(FaultRule{Kind: FaultNoAck, Count: 2})
+	device := Device(bus, retryDevice).WithRetryPolicy(policy)
+
+	if _, err := device.ReadByteRegister(0); err != nil {
+		t.Fatalf("Read failed after retries: %v", err)
+	}
+
+	if policy.Retries() != 2 || policy.Failures() != 0 {
+		t.Errorf("Retries %d failures %d, expected 2 and 0", policy.Retries(), policy.Failures())
+	}
+
+	bus.AddRule(FaultRule{Kind: FaultNoAck})
+
+	if _, err := device.ReadByteRegister(0); !errors.Is(err, ErrNoAck) {
+		t.Fatalf("Read returned %v, expected no acknowledge", err)
+	}
+
+	if policy.Retries() != 4 || policy.Failures() != 1 {
+		t.Errorf("Retries %d failures %d, expected 4 and 1", policy.Retries(), policy.Failures())
+	}
+}
+
+func TestRetryWrites(t *testing.T) {
+	policy := &RetryPolicy{Attempts: 3}
+	device := Device(retryBus(FaultRule{Kind: FaultNoAck, Count: 1}), retryDevice).WithRetryPolicy(policy)
+
+	if err := device.WriteByteRegister(0, 1); !errors.Is(err, ErrNoAck) {
+		t.Fatalf("Write returned %v, expected no acknowledge", err)
+	}
+
+	if policy.Retries() != 0 || policy.Failures() != 1 {
+		t.Errorf("Retries %d failures %d, expected 0 and 1", policy.Retries(), policy.Failures())
+	}
+
+	policy.RetryWrites = true
+
+	if err := device.WriteByteRegister(0, 1); err != nil {
+		t.Fatalf("Write failed with RetryWrites: %v", err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+
+	policy := &RetryPolicy{Attempts: 5, Backoff: backoff, MaxBackoff: backoff}
+	device := Device(retryBus(FaultRule{Kind: FaultNoAck}), retryDevice).WithRetryPolicy(policy)
+
+	start := time.Now()
+	if _, err := device.ReadByteRegister(0); !errors.Is(err, ErrNoAck) {
+		t.Fatalf("Read returned %v, expected no acknowledge", err)
+	}
+	elapsed := time.Since(start)
+
+	// Four waits of the capped backoff, without the cap they would take 300ms
+	if elapsed < 4*backoff || elapsed >= 250*time.Millisecond {
+		t.Errorf("Retries took %v, expected about %v", elapsed, 4*backoff)
+	}
+
+	if policy.Retries() != 4 || policy.Failures() != 1 {
+		t.Errorf("Retries %d failures %d, expected 4 and 1", policy.Retries(), policy.Failures())
+	}
+}
@@ -0,0 +1,194 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProbeMode - how an address is probed for a device
+type ProbeMode int
+
+// Probe modes
+const (
+	ProbeAuto       ProbeMode = iota // As done by i2cdetect: read byte for 0x30-0x37 and 0x50-0x5f, quick write otherwise
+	ProbeQuickWrite                  // Send the address with the write bit and no data
+	ProbeReadByte                    // Read one byte
+)
+
+// Default scan range, addresses outside it are reserved by the I2C specification
+const (
+	FirstScanAddress = 0x08
+	LastScanAddress  = 0x77
+)
+
+// ScanOptions - options controlling a bus scan. The zero value scans the default range using ProbeAuto
+type ScanOptions struct {
+	First           byte // First address to scan, the default range is used if both First and Last are 0
+	Last            byte // Last address to scan
+	Mode            ProbeMode
+	IncludeReserved bool // Also probe reserved addresses (0x00-0x07 and 0x78-0x7f) that are in the range
+}
+
+// Implemented by buses that can report the adapter capabilities
+type capabilitiesBus interface {
+	Capabilities() Capabilities
+}
+
+// Implemented by buses that can tell if an address is used by a kernel driver
+type inUseBus interface {
+	InUse(address byte) (bool, error)
+}
+
+// InUse - return true if the device at a given address is used by a kernel driver (shown as UU by i2cdetect).
+// Selecting such an address with I2C_SLAVE fails with EBUSY
+func (bus *I2Cbus) InUse(address byte) (bool, error) {
+	if err := checkAddress(uint16(address), false); err != nil {
+		return false, err
+	}
+
+	if err := bus.lock(); err != nil {
+		return false, err
+	}
+	defer bus.unlock()
+
+	if err := bus.setCurrentDeviceAddress(address); err != nil {
+		if errors.Is(err, unix.EBUSY) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return false, nil
+}
+
+func (options ScanOptions) bounds() (byte, byte) {
+	if options.First == 0 && options.Last == 0 {
+		return FirstScanAddress, LastScanAddress
+	}
+
+	return options.First, options.Last
+}
+
+// IsReservedAddress - return true if the address is reserved by the I2C specification
+func IsReservedAddress(address byte) bool {
+	return address < FirstScanAddress || address > LastScanAddress
+}
+
+// IsWriteSensitiveAddress - return true if the address is used by devices that can be corrupted by writes that
+// are not meant for them. A quick write or a register pointer write can corrupt EEPROMs (0x50-0x5f), and lock
+// some write only devices (0x30-0x37)
+func IsWriteSensitiveAddress(address byte) bool {
+	return (address >= 0x30 && address <= 0x37) || (address >= 0x50 && address <= 0x5f)
+}
+
+// Get probe mode to use for a given address
+func probeMode(bus Bus, address byte, mode ProbeMode) ProbeMode {
+	if mode != ProbeAuto {
+		return mode
+	}
+
+	if IsWriteSensitiveAddress(address) {
+		return ProbeReadByte
+	}
+
+	if bus, ok := bus.(capabilitiesBus); ok && !bus.Capabilities().Has(CapabilitySMBusQuick) {
+		return ProbeReadByte
+	}
+
+	return ProbeQuickWrite
+}
+
+// Return true if the error means that there is no usable device at the address
+func isAbsent(err error) bool {
+	return errors.Is(err, ErrNoAck) || errors.Is(err, ErrBusBusy) || errors.Is(err, ErrTimeout)
+}
+
+func probe(bus Bus, address uint16, flags MessageFlags, mode ProbeMode) (bool, error) {
+	message := Message{Address: address, Flags: flags}
+
+	if mode == ProbeReadByte {
+		message.Flags |= MessageRead
+		message.Buffer = make([]byte, 1)
+	}
+
+	if err := bus.Transfer(message); err != nil {
+		if isAbsent(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Probe - return true if a device acknowledges a given address. If the bus can tell that the address is used by a
+// kernel driver (I2Cbus can, see InUse), the address is not probed and is reported as absent. Probes done
+// through other buses, such as bus wrappers, reach addresses used by kernel drivers
+func Probe(bus Bus, address byte, mode ProbeMode) (bool, error) {
+	if address > MaxAddress {
+		return false, I2CdeviceError{address, fmt.Sprintf("Address %#x is larger than %#x", address, MaxAddress), nil}
+	}
+
+	if bus, ok := bus.(inUseBus); ok {
+		if inUse, err := bus.InUse(address); err != nil || inUse {
+			return false, err
+		}
+	}
+
+	return probe(bus, uint16(address), 0, probeMode(bus, address, mode))
+}
+
+// Scan - return the addresses acknowledged by a device, following the rules used by i2cdetect. Scanning stops
+// on errors other than no acknowledge, busy or timeout
+func Scan(bus Bus, options ScanOptions) ([]byte, error) {
+	first, last := options.bounds()
+	found := make([]byte, 0, 8)
+
+	if last > MaxAddress {
+		last = MaxAddress
+	}
+
+	for address := int(first); address <= int(last); address++ {
+		if IsReservedAddress(byte(address)) && !options.IncludeReserved {
+			continue
+		}
+
+		present, err := Probe(bus, byte(address), options.Mode)
+		if err != nil {
+			return found, err
+		}
+
+		if present {
+			found = append(found, byte(address))
+		}
+	}
+
+	return found, nil
+}
+
+// Scan10 - return the 10 bit addresses in a given range acknowledged by a device. Addresses are probed using quick
+// write. The adapter must support 10 bit addressing
+func Scan10(bus Bus, first uint16, last uint16) ([]uint16, error) {
+	found := make([]uint16, 0, 8)
+
+	if last > MaxTenBitAddress {
+		last = MaxTenBitAddress
+	}
+
+	for address := int(first); address <= int(last); address++ {
+		present, err := probe(bus, uint16(address), MessageTenBit, ProbeQuickWrite)
+		if err != nil {
+			return found, err
+		}
+
+		if present {
+			found = append(found, uint16(address))
+		}
+	}
+
+	return found, nil
+}
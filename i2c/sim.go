@@ -0,0 +1,381 @@
+package i2c
+
+import (
+	"sync"
+)
+
+// SimDevice - a virtual device that can be attached to a SimBus
+type SimDevice interface {
+	// Write - handle data written to the device
+	Write(data []byte) error
+
+	// Read - fill buffer with data read from the device
+	Read(buffer []byte) error
+}
+
+// SimBus - in memory simulated I2C bus
+//
+// Virtual devices are attached to the bus at given 7 or 10 bit addresses. Accessing an address with no attached
+// device fails as if the device did not acknowledge. SimBus implements Bus, so it can be used instead of I2Cbus by
+// I2Cdevice and by the device drivers
+//
+type SimBus struct {
+	busMutex     sync.Mutex           // Held for the duration of a bus operation
+	devicesMutex sync.Mutex           // Protects devices
+	devices      map[uint16]SimDevice // Keyed by address, 10 bit addresses have simTenBit set
+	bridges      []SimBridge
+}
+
+// SimBridge - connects devices on other buses to a SimBus, for example the selected channels of a multiplexer
+type SimBridge interface {
+	// Lookup - return the device reachable through the bridge at a given address
+	Lookup(address uint16, tenBit bool) (SimDevice, bool)
+}
+
+const simTenBit = 0x8000
+
+// NewSimBus - create a simulated bus with no devices attached
+func NewSimBus() *SimBus {
+	return &SimBus{devices: make(map[uint16]SimDevice)}
+}
+
+func simKey(address uint16, tenBit bool) uint16 {
+	if tenBit {
+		return address | simTenBit
+	}
+
+	return address
+}
+
+func (bus *SimBus) attach(key uint16, device SimDevice) {
+	bus.devicesMutex.Lock()
+	defer bus.devicesMutex.Unlock()
+
+	bus.devices[key] = device
+}
+
+func (bus *SimBus) detach(key uint16) {
+	bus.devicesMutex.Lock()
+	defer bus.devicesMutex.Unlock()
+
+	delete(bus.devices, key)
+}
+
+func (bus *SimBus) attached(key uint16) (SimDevice, bool) {
+	bus.devicesMutex.Lock()
+	defer bus.devicesMutex.Unlock()
+
+	device, found := bus.devices[key]
+	return device, found
+}
+
+// Attach - attach a virtual device at a given address, replacing any device already attached there
+func (bus *SimBus) Attach(address byte, device SimDevice) {
+	bus.attach(simKey(uint16(address), false), device)
+}
+
+// Detach - remove the device attached at a given address
+func (bus *SimBus) Detach(address byte) {
+	bus.detach(simKey(uint16(address), false))
+}
+
+// Attached - return the device attached at a given address
+func (bus *SimBus) Attached(address byte) (SimDevice, bool) {
+	return bus.attached(simKey(uint16(address), false))
+}
+
+// AttachBridge - make the devices reachable through a bridge accessible on the bus. Devices attached to the bus
+// itself are found first
+func (bus *SimBus) AttachBridge(bridge SimBridge) {
+	bus.devicesMutex.Lock()
+	defer bus.devicesMutex.Unlock()
+
+	bus.bridges = append(bus.bridges, bridge)
+}
+
+// Lookup - return the device at a given 7 or 10 bit address, either attached to the bus or reachable through
+// one of its bridges
+func (bus *SimBus) Lookup(address uint16, tenBit bool) (SimDevice, bool) {
+	if device, found := bus.attached(simKey(address, tenBit)); found {
+		return device, true
+	}
+
+	bus.devicesMutex.Lock()
+	bridges := append([]SimBridge(nil), bus.bridges...)
+	bus.devicesMutex.Unlock()
+
+	for _, bridge := range bridges {
+		if device, found := bridge.Lookup(address, tenBit); found {
+			return device, true
+		}
+	}
+
+	return nil, false
+}
+
+// Attach10 - attach a virtual device at a given 10 bit address, replacing any device already attached there
+func (bus *SimBus) Attach10(address uint16, device SimDevice) {
+	bus.attach(simKey(address, true), device)
+}
+
+// Detach10 - remove the device attached at a given 10 bit address
+func (bus *SimBus) Detach10(address uint16) {
+	bus.detach(simKey(address, true))
+}
+
+// Attached10 - return the device attached at a given 10 bit address
+func (bus *SimBus) Attached10(address uint16) (SimDevice, bool) {
+	return bus.attached(simKey(address, true))
+}
+
+// Device - Get device object for a given device address
+func (bus *SimBus) Device(address byte) I2Cdevice {
+	return Device(bus, address)
+}
+
+func (bus *SimBus) lookup(address uint16, tenBit bool, description string) (SimDevice, error) {
+	if err := checkAddress(address, tenBit); err != nil {
+		return nil, err
+	}
+
+	if device, found := bus.Lookup(address, tenBit); found {
+		return device, nil
+	}
+
+	return nil, I2CdeviceError{byte(address), description, ErrNoAck}
+}
+
+func (bus *SimBus) readFrom(address uint16, tenBit bool, buffer []byte) (int, error) {
+	device, err := bus.lookup(address, tenBit, "Read")
+	if err != nil {
+		return 0, err
+	}
+
+	if err := device.Read(buffer); err != nil {
+		return 0, I2CdeviceError{byte(address), "Read", err}
+	}
+
+	return len(buffer), nil
+}
+
+func (bus *SimBus) writeTo(address uint16, tenBit bool, buffer []byte) (int, error) {
+	device, err := bus.lookup(address, tenBit, "Write")
+	if err != nil {
+		return 0, err
+	}
+
+	if err := device.Write(buffer); err != nil {
+		return 0, I2CdeviceError{byte(address), "Write", err}
+	}
+
+	return len(buffer), nil
+}
+
+func (bus *SimBus) read(address byte, buffer []byte) (int, error) {
+	return bus.readFrom(uint16(address), false, buffer)
+}
+
+func (bus *SimBus) write(address byte, buffer []byte) (int, error) {
+	return bus.writeTo(uint16(address), false, buffer)
+}
+
+// Read - read from the device at a given address
+func (bus *SimBus) Read(address byte, buffer []byte) (int, error) {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return bus.read(address, buffer)
+}
+
+// Write - write to the device at a given address
+func (bus *SimBus) Write(address byte, buffer []byte) (int, error) {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return bus.write(address, buffer)
+}
+
+// Transfer - perform messages as one transaction. No other operation can access the bus until all the
+// messages are done
+func (bus *SimBus) Transfer(messages ...Message) error {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return bus.transfer(messages)
+}
+
+func (bus *SimBus) transfer(messages []Message) error {
+	for _, message := range messages {
+		var err error
+
+		tenBit := message.Flags&MessageTenBit != 0
+		if message.Flags&MessageRead != 0 {
+			_, err = bus.readFrom(message.Address, tenBit, message.Buffer)
+		} else {
+			_, err = bus.writeTo(message.Address, tenBit, message.Buffer)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Tx - run fn with exclusive access to the bus
+func (bus *SimBus) Tx(fn func(bus Bus) error) error {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return fn(simBusTx{bus})
+}
+
+// Close - nothing to release for simulated bus
+func (bus *SimBus) Close() error {
+	return nil
+}
+
+// Bus passed to Tx functions, operations are done with the bus mutex already held
+type simBusTx struct {
+	bus *SimBus
+}
+
+func (tx simBusTx) Read(address byte, buffer []byte) (int, error) {
+	return tx.bus.read(address, buffer)
+}
+
+func (tx simBusTx) Write(address byte, buffer []byte) (int, error) {
+	return tx.bus.write(address, buffer)
+}
+
+func (tx simBusTx) Transfer(messages ...Message) error {
+	return tx.bus.transfer(messages)
+}
+
+func (tx simBusTx) Tx(fn func(bus Bus) error) error {
+	return fn(tx)
+}
+
+func (tx simBusTx) Close() error {
+	return errCloseInTx
+}
+
+// SimRegisterDevice - virtual device backed by a 16 bit addressed register file
+//
+// The first two bytes of a write (big endian) set the register pointer, or only the first byte if Address8 is
+// set. Any additional bytes are stored in consecutive registers. A read returns consecutive registers starting
+// at the register pointer. The pointer is incremented after each register access, as done by the VL6180x.
+// Registers that were never written read as 0
+//
+type SimRegisterDevice struct {
+	mutex     sync.Mutex
+	registers map[uint16]byte
+	pointer   uint16
+
+	// Address8 - register addresses are sent as a single byte
+	Address8 bool
+
+	// OnRead - if not nil, called for each register read from the bus. The returned value is sent to the bus
+	OnRead func(register uint16, value byte) byte
+
+	// OnWrite - if not nil, called for each register written from the bus. The returned value is stored
+	OnWrite func(register uint16, value byte) byte
+}
+
+// NewSimRegisterDevice - create virtual device with all registers set to 0
+func NewSimRegisterDevice() *SimRegisterDevice {
+	return &SimRegisterDevice{registers: make(map[uint16]byte)}
+}
+
+// Register - get register value without invoking the hooks
+func (device *SimRegisterDevice) Register(register uint16) byte {
+	device.mutex.Lock()
+	defer device.mutex.Unlock()
+
+	return device.registers[register]
+}
+
+// SetRegister - set register value without invoking the hooks
+func (device *SimRegisterDevice) SetRegister(register uint16, value byte) {
+	device.mutex.Lock()
+	defer device.mutex.Unlock()
+
+	device.registers[register] = value
+}
+
+// SetRegisters - set consecutive registers starting at a given register without invoking the hooks
+func (device *SimRegisterDevice) SetRegisters(register uint16, values ...byte) {
+	device.mutex.Lock()
+	defer device.mutex.Unlock()
+
+	for i, value := range values {
+		device.registers[register+uint16(i)] = value
+	}
+}
+
+// Reset - set all registers to 0
+func (device *SimRegisterDevice) Reset() {
+	device.mutex.Lock()
+	defer device.mutex.Unlock()
+
+	device.registers = make(map[uint16]byte)
+	device.pointer = 0
+}
+
+func (device *SimRegisterDevice) nextRegister() uint16 {
+	device.mutex.Lock()
+	defer device.mutex.Unlock()
+
+	register := device.pointer
+	device.pointer++
+	return register
+}
+
+// Write - set register pointer and store any additional bytes in the registers
+func (device *SimRegisterDevice) Write(data []byte) error {
+	addressLength := 2
+	if device.Address8 {
+		addressLength = 1
+	}
+
+	if len(data) < addressLength {
+		return nil // Probe or incomplete register address, just acknowledge
+	}
+
+	device.mutex.Lock()
+	if device.Address8 {
+		device.pointer = uint16(data[0])
+	} else {
+		device.pointer = (uint16(data[0]) << 8) | uint16(data[1])
+	}
+	device.mutex.Unlock()
+
+	for _, value := range data[addressLength:] {
+		register := device.nextRegister()
+
+		if device.OnWrite != nil {
+			value = device.OnWrite(register, value)
+		}
+
+		device.SetRegister(register, value)
+	}
+
+	return nil
+}
+
+// Read - read registers starting at the register pointer
+func (device *SimRegisterDevice) Read(buffer []byte) error {
+	for i := range buffer {
+		register := device.nextRegister()
+		value := device.Register(register)
+
+		if device.OnRead != nil {
+			value = device.OnRead(register, value)
+		}
+
+		buffer[i] = value
+	}
+
+	return nil
+}
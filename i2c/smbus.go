@@ -0,0 +1,260 @@
+package i2c
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlI2cFuncs uint = 0x00000705
+	ioctlI2cPec   uint = 0x00000708
+	ioctlI2cSmbus uint = 0x00000720
+)
+
+// SMBus transfer direction and types - see linux/i2c.h
+const (
+	smbusWrite = 0
+	smbusRead  = 1
+
+	smbusQuick         = 0
+	smbusByte          = 1
+	smbusByteData      = 2
+	smbusWordData      = 3
+	smbusProcCall      = 4
+	smbusBlockData     = 5
+	smbusBlockProcCall = 7
+	smbusI2cBlockData  = 8
+)
+
+// SMBusBlockMax - maximum number of data bytes in SMBus block transfer
+const SMBusBlockMax = 32
+
+// union i2c_smbus_data - see linux/i2c.h
+type smbusData [SMBusBlockMax + 2]byte
+
+// i2c_smbus_ioctl_data - see linux/i2c-dev.h
+type smbusIoctlData struct {
+	readWrite uint8
+	command   uint8
+	size      uint32
+	data      *smbusData
+}
+
+func (data *smbusData) word() uint16 {
+	return *(*uint16)(unsafe.Pointer(&data[0])) // Native byte order, as the kernel stores it
+}
+
+func (data *smbusData) setWord(value uint16) {
+	*(*uint16)(unsafe.Pointer(&data[0])) = value
+}
+
+func (data *smbusData) block() []byte {
+	length := int(data[0])
+	if length > SMBusBlockMax {
+		length = SMBusBlockMax
+	}
+
+	return append([]byte(nil), data[1:1+length]...)
+}
+
+func (data *smbusData) setBlock(block []byte) {
+	data[0] = byte(len(block))
+	copy(data[1:], block)
+}
+
+// Issue SMBus command to a device with 7 or 10 bit address, the bus must be locked
+func (bus *I2Cbus) smbusCommand(address uint16, tenBit bool, readWrite uint8, command byte, size uint32, data *smbusData) error {
+	if err := bus.selectDevice(address, tenBit); err != nil {
+		return err
+	}
+
+	arguments := smbusIoctlData{readWrite: readWrite, command: command, size: size, data: data}
+	if _, err := bus.ioctl("I2C_SMBUS", ioctlI2cSmbus, unsafe.Pointer(&arguments)); err != nil {
+		return I2CdeviceError{byte(address), fmt.Sprintf("SMBus command %#02x", command), err}
+	}
+
+	return nil
+}
+
+func (bus *I2Cbus) smbusAccess(address byte, readWrite uint8, command byte, size uint32, data *smbusData) error {
+	return bus.locked(readWrite == smbusWrite, func() error {
+		return bus.smbusCommand(uint16(address), false, readWrite, command, size, data)
+	})
+}
+
+func checkSMBusBlock(address byte, block []byte) error {
+	if len(block) > SMBusBlockMax {
+		return I2CdeviceError{address, fmt.Sprintf("SMBus block of %d bytes is longer than %d", len(block), SMBusBlockMax), nil}
+	}
+
+	return nil
+}
+
+// SetPEC - enable or disable SMBus packet error checking. Fails if the adapter does not support PEC
+func (bus *I2Cbus) SetPEC(enable bool) error {
+	if enable && !bus.capabilities.Has(CapabilitySMBusPEC) {
+		return BusError{bus.i2cHandle.Name(), "Enable SMBus PEC", ErrNotSupported}
+	}
+
+	var value int
+	if enable {
+		value = 1
+	}
+
+	if err := bus.lock(); err != nil {
+		return err
+	}
+	defer bus.unlock()
+
+	if err := unix.IoctlSetInt(int(bus.i2cHandle.Fd()), ioctlI2cPec, value); err != nil {
+		return BusError{bus.i2cHandle.Name(), "Set SMBus PEC", &os.PathError{Op: "ioctl I2C_PEC", Path: bus.i2cHandle.Name(), Err: err}}
+	}
+
+	return nil
+}
+
+// SMBusQuick - SMBus quick command, the read/write bit is the only data sent to the device
+func (bus *I2Cbus) SMBusQuick(address byte, read bool) error {
+	var readWrite uint8 = smbusWrite
+	if read {
+		readWrite = smbusRead
+	}
+
+	return bus.smbusAccess(address, readWrite, 0, smbusQuick, nil)
+}
+
+// SMBusReadByte - SMBus receive byte
+func (bus *I2Cbus) SMBusReadByte(address byte) (byte, error) {
+	var data smbusData
+
+	if err := bus.smbusAccess(address, smbusRead, 0, smbusByte, &data); err != nil {
+		return 0, err
+	}
+
+	return data[0], nil
+}
+
+// SMBusWriteByte - SMBus send byte
+func (bus *I2Cbus) SMBusWriteByte(address byte, value byte) error {
+	return bus.smbusAccess(address, smbusWrite, value, smbusByte, nil)
+}
+
+// SMBusReadByteData - SMBus read byte from a given command code
+func (bus *I2Cbus) SMBusReadByteData(address byte, command byte) (byte, error) {
+	var data smbusData
+
+	if err := bus.smbusAccess(address, smbusRead, command, smbusByteData, &data); err != nil {
+		return 0, err
+	}
+
+	return data[0], nil
+}
+
+// SMBusWriteByteData - SMBus write byte to a given command code
+func (bus *I2Cbus) SMBusWriteByteData(address byte, command byte, value byte) error {
+	var data smbusData
+
+	data[0] = value
+	return bus.smbusAccess(address, smbusWrite, command, smbusByteData, &data)
+}
+
+// SMBusReadWordData - SMBus read word from a given command code
+func (bus *I2Cbus) SMBusReadWordData(address byte, command byte) (uint16, error) {
+	var data smbusData
+
+	if err := bus.smbusAccess(address, smbusRead, command, smbusWordData, &data); err != nil {
+		return 0, err
+	}
+
+	return data.word(), nil
+}
+
+// SMBusWriteWordData - SMBus write word to a given command code
+func (bus *I2Cbus) SMBusWriteWordData(address byte, command byte, value uint16) error {
+	var data smbusData
+
+	data.setWord(value)
+	return bus.smbusAccess(address, smbusWrite, command, smbusWordData, &data)
+}
+
+// SMBusProcessCall - SMBus process call, write a word to a given command code and read back a word
+func (bus *I2Cbus) SMBusProcessCall(address byte, command byte, value uint16) (uint16, error) {
+	var data smbusData
+
+	data.setWord(value)
+	if err := bus.smbusAccess(address, smbusWrite, command, smbusProcCall, &data); err != nil {
+		return 0, err
+	}
+
+	return data.word(), nil
+}
+
+// SMBusReadBlockData - SMBus block read, the device returns the number of bytes it sends (up to 32)
+func (bus *I2Cbus) SMBusReadBlockData(address byte, command byte) ([]byte, error) {
+	var data smbusData
+
+	if err := bus.smbusAccess(address, smbusRead, command, smbusBlockData, &data); err != nil {
+		return nil, err
+	}
+
+	return data.block(), nil
+}
+
+// SMBusWriteBlockData - SMBus block write of up to 32 bytes, the byte count is sent before the data
+func (bus *I2Cbus) SMBusWriteBlockData(address byte, command byte, block []byte) error {
+	var data smbusData
+
+	if err := checkSMBusBlock(address, block); err != nil {
+		return err
+	}
+
+	data.setBlock(block)
+	return bus.smbusAccess(address, smbusWrite, command, smbusBlockData, &data)
+}
+
+// SMBusBlockProcessCall - SMBus block write followed by block read
+func (bus *I2Cbus) SMBusBlockProcessCall(address byte, command byte, block []byte) ([]byte, error) {
+	var data smbusData
+
+	if err := checkSMBusBlock(address, block); err != nil {
+		return nil, err
+	}
+
+	data.setBlock(block)
+	if err := bus.smbusAccess(address, smbusWrite, command, smbusBlockProcCall, &data); err != nil {
+		return nil, err
+	}
+
+	return data.block(), nil
+}
+
+// SMBusReadI2CBlockData - read length (up to 32) bytes from a given command code, without SMBus byte count
+func (bus *I2Cbus) SMBusReadI2CBlockData(address byte, command byte, length int) ([]byte, error) {
+	var data smbusData
+
+	if length < 0 || length > SMBusBlockMax {
+		return nil, I2CdeviceError{address, fmt.Sprintf("SMBus I2C block read of %d bytes is not between 0...%d", length, SMBusBlockMax), nil}
+	}
+
+	data[0] = byte(length)
+	if err := bus.smbusAccess(address, smbusRead, command, smbusI2cBlockData, &data); err != nil {
+		return nil, err
+	}
+
+	return data.block(), nil
+}
+
+// SMBusWriteI2CBlockData - write up to 32 bytes to a given command code, without SMBus byte count
+func (bus *I2Cbus) SMBusWriteI2CBlockData(address byte, command byte, block []byte) error {
+	var data smbusData
+
+	if err := checkSMBusBlock(address, block); err != nil {
+		return err
+	}
+
+	data.setBlock(block)
+	return bus.smbusAccess(address, smbusWrite, command, smbusI2cBlockData, &data)
+}
@@ -0,0 +1,142 @@
+package i2c
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SysfsAdapterRoot - directory listing the I2C adapters known to the kernel
+const SysfsAdapterRoot = "/sys/class/i2c-adapter"
+
+const devicetreeBase = "/devicetree/base"
+
+// Adapter - I2C adapter (bus) as described by sysfs
+type Adapter struct {
+	Number         int    // Bus number, the N in /dev/i2c-N
+	Name           string // Adapter name, for example "bcm2835 (i2c@7e804000)"
+	DeviceTreePath string // Device tree node of the adapter, for example "/soc/i2c@7e804000". Empty if none
+}
+
+// DevicePath - path of the adapter's device node
+func (adapter Adapter) DevicePath() string {
+	return fmt.Sprint("/dev/i2c-", adapter.Number)
+}
+
+// Matches - return true if name is the adapter name, its device tree path or the last element of the device
+// tree path
+func (adapter Adapter) Matches(name string) bool {
+	if name == adapter.Name {
+		return true
+	}
+
+	return adapter.DeviceTreePath != "" && (name == adapter.DeviceTreePath || name == path.Base(adapter.DeviceTreePath))
+}
+
+// ListAdapters - list the I2C adapters, ordered by bus number
+func ListAdapters() ([]Adapter, error) {
+	return ListAdaptersAt(SysfsAdapterRoot)
+}
+
+// ListAdaptersAt - list the I2C adapters described by a sysfs i2c-adapter directory (use for testing against
+// a fake sysfs tree). Each i2c-N entry has a name file, and optionally an of_node link to the device tree node
+func ListAdaptersAt(root string) ([]Adapter, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, BusError{root, "List adapters", err}
+	}
+
+	adapters := make([]Adapter, 0, len(entries))
+
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "i2c-") {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "i2c-"))
+		if err != nil {
+			continue
+		}
+
+		entryPath := filepath.Join(root, entry.Name())
+		name, err := ioutil.ReadFile(filepath.Join(entryPath, "name"))
+		if err != nil {
+			return nil, BusError{entryPath, "Get adapter name", err}
+		}
+
+		adapters = append(adapters, Adapter{
+			Number:         number,
+			Name:           strings.TrimSpace(string(name)),
+			DeviceTreePath: deviceTreePath(filepath.Join(entryPath, "of_node")),
+		})
+	}
+
+	sort.Slice(adapters, func(i, j int) bool { return adapters[i].Number < adapters[j].Number })
+	return adapters, nil
+}
+
+// Get device tree path from of_node link, which points into /sys/firmware/devicetree/base
+func deviceTreePath(ofNode string) string {
+	target, err := os.Readlink(ofNode)
+	if err != nil {
+		return ""
+	}
+
+	target = filepath.ToSlash(target)
+	if index := strings.Index(target, devicetreeBase); index >= 0 {
+		target = target[index+len(devicetreeBase):]
+	}
+
+	return path.Clean("/" + strings.TrimLeft(target, "./"))
+}
+
+// FindAdapter - find the adapter matching a given name (see Adapter.Matches)
+func FindAdapter(name string) (Adapter, error) {
+	return FindAdapterAt(SysfsAdapterRoot, name)
+}
+
+// FindAdapterAt - find the adapter matching a given name in a sysfs i2c-adapter directory. Fails if no adapter
+// or more than one adapter matches
+func FindAdapterAt(root string, name string) (Adapter, error) {
+	adapters, err := ListAdaptersAt(root)
+	if err != nil {
+		return Adapter{}, err
+	}
+
+	var found []Adapter
+
+	for _, adapter := range adapters {
+		if adapter.Matches(name) {
+			found = append(found, adapter)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return Adapter{}, BusError{name, "Find adapter", ErrAdapterMissing}
+	case 1:
+		return found[0], nil
+	}
+
+	numbers := make([]string, len(found))
+	for i, adapter := range found {
+		numbers[i] = strconv.Itoa(adapter.Number)
+	}
+
+	return Adapter{}, BusError{name, "Find adapter", fmt.Errorf("name matches adapters %s", strings.Join(numbers, ", "))}
+}
+
+// OpenByName - open the bus of the adapter matching a given name (see Adapter.Matches)
+func OpenByName(name string, options ...Option) (*I2Cbus, error) {
+	adapter, err := FindAdapter(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenPath(adapter.DevicePath(), options...)
+}
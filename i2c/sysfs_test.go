@@ -0,0 +1,106 @@
+package i2c
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Create a fake sysfs tree with the i2c-adapter directory and the device tree nodes its of_node links point to
+func fakeSysfs(t *testing.T) (string, func()) {
+	sysfs, err := ioutil.TempDir("", "sysfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(sysfs, "class", "i2c-adapter")
+
+	adapter := func(entry string, name string, node string) {
+		entryPath := filepath.Join(root, entry)
+
+		if err := os.MkdirAll(entryPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(entryPath, "name"), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if node != "" {
+			target := filepath.Join(sysfs, "firmware", "devicetree", "base", node)
+			if err := os.MkdirAll(target, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Symlink(target, filepath.Join(entryPath, "of_node")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	adapter("i2c-11", "bcm2835 (i2c@7e205000)", "soc/i2c@7e205000")
+	adapter("i2c-1", "bcm2835 (i2c@7e804000)", "soc/i2c@7e804000")
+	adapter("i2c-2", "i2c-mux", "")
+	adapter("i2c-3", "i2c-mux", "")
+
+	if err := os.MkdirAll(filepath.Join(root, "unrelated"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return root, func() { os.RemoveAll(sysfs) }
+}
+
+func TestListAdaptersAt(t *testing.T) {
+	root, cleanup := fakeSysfs(t)
+	defer cleanup()
+
+	adapters, err := ListAdaptersAt(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Adapter{
+		{Number: 1, Name: "bcm2835 (i2c@7e804000)", DeviceTreePath: "/soc/i2c@7e804000"},
+		{Number: 2, Name: "i2c-mux"},
+		{Number: 3, Name: "i2c-mux"},
+		{Number: 11, Name: "bcm2835 (i2c@7e205000)", DeviceTreePath: "/soc/i2c@7e205000"},
+	}
+
+	if len(adapters) != len(expected) {
+		t.Fatalf("Listed %v, expected %v", adapters, expected)
+	}
+
+	for i := range expected {
+		if adapters[i] != expected[i] {
+			t.Errorf("Adapter %d is %+v, expected %+v", i, adapters[i], expected[i])
+		}
+	}
+
+	if path := adapters[0].DevicePath(); path != "/dev/i2c-1" {
+		t.Errorf("DevicePath is %s, expected /dev/i2c-1", path)
+	}
+}
+
+func TestFindAdapterAt(t *testing.T) {
+	root, cleanup := fakeSysfs(t)
+	defer cleanup()
+
+	for _, name := range []string{"bcm2835 (i2c@7e804000)", "/soc/i2c@7e804000", "i2c@7e804000"} {
+		adapter, err := FindAdapterAt(root, name)
+		if err != nil {
+			t.Errorf("FindAdapterAt(%q): %v", name, err)
+		} else if adapter.Number != 1 {
+			t.Errorf("FindAdapterAt(%q) found adapter %d, expected 1", name, adapter.Number)
+		}
+	}
+
+	if _, err := FindAdapterAt(root, "missing"); !errors.Is(err, ErrAdapterMissing) {
+		t.Errorf("FindAdapterAt of missing adapter returned %v", err)
+	}
+
+	if _, err := FindAdapterAt(root, "i2c-mux"); err == nil {
+		t.Error("FindAdapterAt of ambiguous name did not fail")
+	}
+}
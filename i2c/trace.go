@@ -0,0 +1,283 @@
+package i2c
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceDirection - direction of the data of a traced operation
+type TraceDirection int
+
+// Trace directions
+const (
+	TraceWrite TraceDirection = iota // Data is written to the device
+	TraceRead                        // Data is read from the device (a register read also writes the register address)
+)
+
+func (direction TraceDirection) String() string {
+	if direction == TraceRead {
+		return "read"
+	}
+
+	return "write"
+}
+
+// TraceEvent - a bus operation done through a TracingBus
+type TraceEvent struct {
+	Time        time.Time      // Operation start time
+	Operation   string         // Read, Write or Transfer
+	Direction   TraceDirection // Read if any of the messages reads
+	Register    uint16         // Register accessed by the operation, valid if HasRegister is true
+	HasRegister bool           // The operation starts with a write of a register address
+	Messages    []Message      // Messages of the operation, read buffers hold the data read
+	Err         error          // Error returned by the operation
+	Latency     time.Duration  // Time it took to complete the operation
+}
+
+// Address - address of the device accessed by the operation
+func (event TraceEvent) Address() uint16 {
+	if len(event.Messages) == 0 {
+		return 0
+	}
+
+	return event.Messages[0].Address
+}
+
+// TraceSink - receives events from a TracingBus. Trace may be called concurrently by several goroutines
+type TraceSink interface {
+	Trace(event TraceEvent)
+}
+
+// TraceSinkFunc - function used as TraceSink
+type TraceSinkFunc func(event TraceEvent)
+
+// Trace - call the function
+func (sink TraceSinkFunc) Trace(event TraceEvent) {
+	sink(event)
+}
+
+// TracingBus - Bus wrapper that reports every operation to a sink
+type TracingBus struct {
+	bus   Bus
+	sink  TraceSink
+	codec RegisterCodec // Register address encoding used to find the register of an operation
+}
+
+// NewTracingBus - get a bus that does the operations on bus and reports them to sink. Registers are decoded
+// using the zero value RegisterCodec (16 bit register addresses), use WithCodec for other devices
+func NewTracingBus(bus Bus, sink TraceSink) *TracingBus {
+	return &TracingBus{bus: bus, sink: sink}
+}
+
+// WithCodec - get tracing bus that decodes the register of each operation using a given codec
+func (bus *TracingBus) WithCodec(codec RegisterCodec) *TracingBus {
+	return &TracingBus{bus: bus.bus, sink: bus.sink, codec: codec}
+}
+
+func copyMessages(messages []Message) []Message {
+	copied := make([]Message, len(messages))
+
+	for i, message := range messages {
+		copied[i] = Message{Address: message.Address, Flags: message.Flags, Buffer: append([]byte(nil), message.Buffer...)}
+	}
+
+	return copied
+}
+
+func (bus *TracingBus) trace(operation string, messages []Message, perform func() error) error {
+	start := time.Now()
+	err := perform()
+
+	event := TraceEvent{
+		Time:      start,
+		Operation: operation,
+		Messages:  copyMessages(messages),
+		Err:       err,
+		Latency:   time.Since(start),
+	}
+
+	if !isWriteTransfer(messages) {
+		event.Direction = TraceRead
+	}
+
+	if len(messages) > 0 && messages[0].Flags&MessageRead == 0 {
+		event.Register, _, event.HasRegister = bus.codec.decodeAddress(messages[0].Buffer)
+	}
+
+	bus.sink.Trace(event)
+	return err
+}
+
+// Read - read from the device at a given address
+func (bus *TracingBus) Read(address byte, buffer []byte) (n int, err error) {
+	err = bus.trace("Read", []Message{{Address: uint16(address), Flags: MessageRead, Buffer: buffer}}, func() error {
+		n, err = bus.bus.Read(address, buffer)
+		return err
+	})
+
+	return
+}
+
+// Write - write to the device at a given address
+func (bus *TracingBus) Write(address byte, buffer []byte) (n int, err error) {
+	err = bus.trace("Write", []Message{{Address: uint16(address), Buffer: buffer}}, func() error {
+		n, err = bus.bus.Write(address, buffer)
+		return err
+	})
+
+	return
+}
+
+// Transfer - perform messages as one transaction
+func (bus *TracingBus) Transfer(messages ...Message) error {
+	return bus.trace("Transfer", messages, func() error {
+		return bus.bus.Transfer(messages...)
+	})
+}
+
+// Tx - run fn with exclusive access to the bus, operations done by fn are traced
+func (bus *TracingBus) Tx(fn func(bus Bus) error) error {
+	return bus.bus.Tx(func(tx Bus) error {
+		return fn(&TracingBus{bus: tx, sink: bus.sink, codec: bus.codec})
+	})
+}
+
+// Close - close the underlying bus
+func (bus *TracingBus) Close() error {
+	return bus.bus.Close()
+}
+
+// WithContext - get tracing bus whose operations are bounded by ctx
+func (bus *TracingBus) WithContext(ctx context.Context) Bus {
+	if contextBus, ok := bus.bus.(ContextBus); ok {
+		return &TracingBus{bus: contextBus.WithContext(ctx), sink: bus.sink, codec: bus.codec}
+	}
+
+	return checkedBus{bus, ctx}
+}
+
+// TraceRecorder - TraceSink that keeps the events in memory
+type TraceRecorder struct {
+	mutex  sync.Mutex
+	events []TraceEvent
+}
+
+// Trace - record event
+func (recorder *TraceRecorder) Trace(event TraceEvent) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.events = append(recorder.events, event)
+}
+
+// Events - get the recorded events
+func (recorder *TraceRecorder) Events() []TraceEvent {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return append([]TraceEvent(nil), recorder.events...)
+}
+
+// Reset - discard the recorded events
+func (recorder *TraceRecorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.events = nil
+}
+
+// RegisterNames - register names by register address, used to annotate traces
+type RegisterNames map[uint16]string
+
+// TraceDecoder - decode trace events to text, annotating register access
+//
+// A write that starts with a register address, and a transfer of register address write followed by a read, are
+// decoded as register access. The register address is decoded using Codec and annotated with the name found
+// in Names (if any)
+//
+type TraceDecoder struct {
+	Codec RegisterCodec
+	Names RegisterNames
+}
+
+func (decoder TraceDecoder) register(register uint16) string {
+	if name, found := decoder.Names[register]; found {
+		return fmt.Sprintf("%#04x %s", register, name)
+	}
+
+	return fmt.Sprintf("%#04x", register)
+}
+
+func hexBytes(data []byte) string {
+	text := make([]string, len(data))
+
+	for i, value := range data {
+		text[i] = fmt.Sprintf("%02x", value)
+	}
+
+	return "[" + strings.Join(text, " ") + "]"
+}
+
+// Decode single message, or register read (write of register address followed by read)
+func (decoder TraceDecoder) messages(messages []Message) []string {
+	var lines []string
+
+	for i := 0; i < len(messages); i++ {
+		message := messages[i]
+
+		if message.Flags&MessageRead != 0 {
+			lines = append(lines, fmt.Sprintf("%#02x R %s", message.Address, hexBytes(message.Buffer)))
+			continue
+		}
+
+		register, length, ok := decoder.Codec.decodeAddress(message.Buffer)
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%#02x W %s", message.Address, hexBytes(message.Buffer)))
+		} else if length == len(message.Buffer) && i+1 < len(messages) && messages[i+1].Flags&MessageRead != 0 && messages[i+1].Address == message.Address {
+			i++
+			lines = append(lines, fmt.Sprintf("%#02x R %s %s", message.Address, decoder.register(register), hexBytes(messages[i].Buffer)))
+		} else if length == len(message.Buffer) {
+			lines = append(lines, fmt.Sprintf("%#02x W %s", message.Address, decoder.register(register)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%#02x W %s %s", message.Address, decoder.register(register), hexBytes(message.Buffer[length:])))
+		}
+	}
+
+	return lines
+}
+
+// Decode - get text describing a trace event
+func (decoder TraceDecoder) Decode(event TraceEvent) string {
+	text := fmt.Sprintf("%s %s", event.Time.Format("15:04:05.000000"), strings.Join(decoder.messages(event.Messages), "; "))
+	text += fmt.Sprintf(" (%v)", event.Latency)
+
+	if event.Err != nil {
+		text += fmt.Sprintf(" error: %v", event.Err)
+	}
+
+	return text
+}
+
+// TextTraceSink - TraceSink that writes a line of text for each event
+type TextTraceSink struct {
+	mutex   sync.Mutex
+	writer  io.Writer
+	decoder TraceDecoder
+}
+
+// NewTextTraceSink - get sink that writes events decoded by decoder to writer
+func NewTextTraceSink(writer io.Writer, decoder TraceDecoder) *TextTraceSink {
+	return &TextTraceSink{writer: writer, decoder: decoder}
+}
+
+// Trace - write event as a line of text
+func (sink *TextTraceSink) Trace(event TraceEvent) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	fmt.Fprintln(sink.writer, sink.decoder.Decode(event))
+}
@@ -0,0 +1,389 @@
+package i2c
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrReplayMismatch - operation done on a ReplayBus does not match the transcript
+var ErrReplayMismatch = errors.New("operation does not match transcript")
+
+// Error classes recorded in transcripts
+var transcriptErrorClasses = []struct {
+	name string
+	err  error
+}{
+	{"NoAck", ErrNoAck},
+	{"Timeout", ErrTimeout},
+	{"BusBusy", ErrBusBusy},
+	{"ShortTransfer", ErrShortTransfer},
+	{"AdapterMissing", ErrAdapterMissing},
+	{"NotSupported", ErrNotSupported},
+}
+
+// TranscriptMessage - message of a transcript entry, Data is hex encoded
+type TranscriptMessage struct {
+	Address uint16       `json:"address"`
+	Flags   MessageFlags `json:"flags"`
+	Data    string       `json:"data"`
+}
+
+// TranscriptEntry - bus operation recorded in a transcript. A transcript is stored as JSON lines, one entry per line
+type TranscriptEntry struct {
+	Time       time.Time           `json:"time"`
+	Operation  string              `json:"op"`
+	Messages   []TranscriptMessage `json:"messages"`
+	Error      string              `json:"error,omitempty"`
+	ErrorClass string              `json:"errorClass,omitempty"` // Error class (such as NoAck) of the error if known
+	Latency    time.Duration       `json:"latencyNs"`
+}
+
+// NewTranscriptEntry - get transcript entry recording a trace event
+func NewTranscriptEntry(event TraceEvent) TranscriptEntry {
+	entry := TranscriptEntry{Time: event.Time, Operation: event.Operation, Latency: event.Latency}
+
+	for _, message := range event.Messages {
+		entry.Messages = append(entry.Messages, TranscriptMessage{Address: message.Address, Flags: message.Flags, Data: hex.EncodeToString(message.Buffer)})
+	}
+
+	if event.Err != nil {
+		entry.Error = event.Err.Error()
+
+		for _, class := range transcriptErrorClasses {
+			if errors.Is(event.Err, class.err) {
+				entry.ErrorClass = class.name
+				break
+			}
+		}
+	}
+
+	return entry
+}
+
+func (entry TranscriptEntry) address() byte {
+	if len(entry.Messages) == 0 {
+		return 0
+	}
+
+	return byte(entry.Messages[0].Address)
+}
+
+// Get the error recorded in the entry, nil if the operation succeeded
+func (entry TranscriptEntry) err() error {
+	if entry.Error == "" {
+		return nil
+	}
+
+	for _, class := range transcriptErrorClasses {
+		if class.name == entry.ErrorClass {
+			return I2CdeviceError{entry.address(), "Replay of: " + entry.Error, class.err}
+		}
+	}
+
+	return I2CdeviceError{entry.address(), "Replay of: " + entry.Error, nil}
+}
+
+// TranscriptWriter - TraceSink writing a transcript. Use with TracingBus to record a session
+type TranscriptWriter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewTranscriptWriter - get transcript writer writing JSON lines to writer
+func NewTranscriptWriter(writer io.Writer) *TranscriptWriter {
+	return &TranscriptWriter{encoder: json.NewEncoder(writer)}
+}
+
+// Trace - write event as transcript entry
+func (transcript *TranscriptWriter) Trace(event TraceEvent) {
+	transcript.mutex.Lock()
+	defer transcript.mutex.Unlock()
+
+	if err := transcript.encoder.Encode(NewTranscriptEntry(event)); err != nil && transcript.err == nil {
+		transcript.err = err
+	}
+}
+
+// Err - get the first error that occurred while writing the transcript
+func (transcript *TranscriptWriter) Err() error {
+	transcript.mutex.Lock()
+	defer transcript.mutex.Unlock()
+
+	return transcript.err
+}
+
+// ReadTranscript - read transcript written by TranscriptWriter
+func ReadTranscript(reader io.Reader) ([]TranscriptEntry, error) {
+	var entries []TranscriptEntry
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var entry TranscriptEntry
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %w", line, err)
+		}
+
+		for _, message := range entry.Messages {
+			if _, err := hex.DecodeString(message.Data); err != nil {
+				return nil, fmt.Errorf("transcript line %d: %w", line, err)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// ReplayBus - Bus answering operations from a transcript
+//
+// Each operation must match the next transcript entry: same operation, addresses, flags, written data and read
+// lengths. Reads are answered with the recorded data, and recorded errors are returned with their error class.
+// An operation that does not match fails with ErrReplayMismatch.
+//
+// Polling loops may run a different number of times than when the transcript was recorded. If Repeats is set,
+// an operation that does not match the next entry is matched with a later entry if all the entries skipped
+// repeat operations that were already replayed. Otherwise, if the operation repeats an operation that was already
+// replayed, it is answered as it was last time without advancing in the transcript
+//
+type ReplayBus struct {
+	busMutex sync.Mutex // Held for the duration of a bus operation
+	mutex    sync.Mutex // Protects the replay state
+	entries  []TranscriptEntry
+	next     int
+	replayed map[string]int // Index of the last replayed entry for each operation key
+	err      error
+
+	// Repeats - tolerate polling loops that run a different number of times (see above)
+	Repeats bool
+}
+
+// NewReplayBus - get bus replaying a transcript
+func NewReplayBus(entries []TranscriptEntry) *ReplayBus {
+	return &ReplayBus{entries: entries, replayed: make(map[string]int)}
+}
+
+// Key identifying operation for matching. Data of read messages is replaced by its length
+func operationKey(operation string, messages []TranscriptMessage) string {
+	key := operation
+
+	for _, message := range messages {
+		data := message.Data
+		if message.Flags&MessageRead != 0 {
+			data = fmt.Sprint(len(data) / 2)
+		}
+
+		key += fmt.Sprintf("|%x,%x,%s", message.Address, message.Flags, data)
+	}
+
+	return key
+}
+
+func (entry TranscriptEntry) key() string {
+	return operationKey(entry.Operation, entry.Messages)
+}
+
+// Find the entry answering operation, return -1 if none
+func (bus *ReplayBus) match(key string) int {
+	if bus.next < len(bus.entries) && bus.entries[bus.next].key() == key {
+		return bus.next
+	}
+
+	if !bus.Repeats {
+		return -1
+	}
+
+	for i := bus.next; i < len(bus.entries); i++ {
+		entryKey := bus.entries[i].key()
+
+		if entryKey == key {
+			return i
+		} else if _, found := bus.replayed[entryKey]; !found {
+			break
+		}
+	}
+
+	if i, found := bus.replayed[key]; found {
+		return i
+	}
+
+	return -1
+}
+
+func (bus *ReplayBus) replay(operation string, messages []Message) error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	recorded := make([]TranscriptMessage, len(messages))
+	for i, message := range messages {
+		data := message.Buffer
+		if message.Flags&MessageRead != 0 {
+			data = make([]byte, len(message.Buffer))
+		}
+
+		recorded[i] = TranscriptMessage{Address: message.Address, Flags: message.Flags, Data: hex.EncodeToString(data)}
+	}
+
+	key := operationKey(operation, recorded)
+	index := bus.match(key)
+
+	if index < 0 {
+		var err error
+
+		if bus.next < len(bus.entries) {
+			err = I2CdeviceError{byte(recorded[0].Address), fmt.Sprintf("%s %v (transcript entry %d is %s %v)", operation, recorded, bus.next+1, bus.entries[bus.next].Operation, bus.entries[bus.next].Messages), ErrReplayMismatch}
+		} else {
+			err = I2CdeviceError{byte(recorded[0].Address), fmt.Sprintf("%s %v (transcript ended)", operation, recorded), ErrReplayMismatch}
+		}
+
+		if bus.err == nil {
+			bus.err = err
+		}
+
+		return err
+	}
+
+	entry := bus.entries[index]
+	bus.replayed[key] = index
+	if index >= bus.next {
+		bus.next = index + 1
+	}
+
+	if err := entry.err(); err != nil {
+		return err
+	}
+
+	for i, message := range messages {
+		if message.Flags&MessageRead != 0 {
+			data, _ := hex.DecodeString(entry.Messages[i].Data)
+			copy(message.Buffer, data)
+		}
+	}
+
+	return nil
+}
+
+// Done - return error if an operation did not match the transcript, or if some of the transcript was not replayed
+func (bus *ReplayBus) Done() error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.err != nil {
+		return bus.err
+	} else if bus.next < len(bus.entries) {
+		return fmt.Errorf("%d of %d transcript entries were not replayed: %w", len(bus.entries)-bus.next, len(bus.entries), ErrReplayMismatch)
+	}
+
+	return nil
+}
+
+func (bus *ReplayBus) read(address byte, buffer []byte) (int, error) {
+	if err := bus.replay("Read", []Message{{Address: uint16(address), Flags: MessageRead, Buffer: buffer}}); err != nil {
+		return 0, err
+	}
+
+	return len(buffer), nil
+}
+
+func (bus *ReplayBus) write(address byte, buffer []byte) (int, error) {
+	if err := bus.replay("Write", []Message{{Address: uint16(address), Buffer: buffer}}); err != nil {
+		return 0, err
+	}
+
+	return len(buffer), nil
+}
+
+func (bus *ReplayBus) transfer(messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return bus.replay("Transfer", messages)
+}
+
+// Read - answer read from the transcript
+func (bus *ReplayBus) Read(address byte, buffer []byte) (int, error) {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return bus.read(address, buffer)
+}
+
+// Write - check write against the transcript
+func (bus *ReplayBus) Write(address byte, buffer []byte) (int, error) {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return bus.write(address, buffer)
+}
+
+// Transfer - answer transfer from the transcript
+func (bus *ReplayBus) Transfer(messages ...Message) error {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return bus.transfer(messages)
+}
+
+// Tx - run fn with exclusive access to the bus
+func (bus *ReplayBus) Tx(fn func(bus Bus) error) error {
+	bus.busMutex.Lock()
+	defer bus.busMutex.Unlock()
+
+	return fn(replayBusTx{bus})
+}
+
+// Close - nothing to release for replay bus
+func (bus *ReplayBus) Close() error {
+	return nil
+}
+
+// WithContext - get Bus whose operations fail if ctx is done before they start
+func (bus *ReplayBus) WithContext(ctx context.Context) Bus {
+	return checkedBus{bus, ctx}
+}
+
+// Bus passed to Tx functions, operations are done with the bus mutex already held
+type replayBusTx struct {
+	bus *ReplayBus
+}
+
+func (tx replayBusTx) Read(address byte, buffer []byte) (int, error) {
+	return tx.bus.read(address, buffer)
+}
+
+func (tx replayBusTx) Write(address byte, buffer []byte) (int, error) {
+	return tx.bus.write(address, buffer)
+}
+
+func (tx replayBusTx) Transfer(messages ...Message) error {
+	return tx.bus.transfer(messages)
+}
+
+func (tx replayBusTx) Tx(fn func(bus Bus) error) error {
+	return fn(tx)
+}
+
+func (tx replayBusTx) Close() error {
+	return errCloseInTx
+}
+
+func (tx replayBusTx) WithContext(ctx context.Context) Bus {
+	return checkedBus{tx, ctx}
+}
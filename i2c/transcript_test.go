@@ -0,0 +1,91 @@
+package i2c
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const transcriptDevice = 0x29
+
+// Register accesses done both when recording and when replaying
+func transcriptSession(bus Bus) ([]byte, error) {
+	device := Device(bus, transcriptDevice)
+
+	if err := device.WriteRegisters(0x0010, []byte{0x12, 0x34, 0x56}); err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, 3)
+	if err := device.ReadRegisters(0x0010, value); err != nil {
+		return nil, err
+	}
+
+	if _, err := Device(bus, transcriptDevice+1).ReadByteRegister(0); !errors.Is(err, ErrNoAck) {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func recordTranscript(t *testing.T) []TranscriptEntry {
+	sim := NewSimBus()
+	sim.Attach(transcriptDevice, NewSimRegisterDevice())
+
+	var transcript bytes.Buffer
+	writer := NewTranscriptWriter(&transcript)
+
+	if _, err := transcriptSession(NewTracingBus(sim, writer)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadTranscript(&transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entries
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	entries := recordTranscript(t)
+
+	if len(entries) != 3 {
+		t.Fatalf("Transcript has %d entries, expected 3", len(entries))
+	}
+
+	if entries[2].ErrorClass != "NoAck" {
+		t.Errorf("Error class of failed read is %q, expected NoAck", entries[2].ErrorClass)
+	}
+
+	replay := NewReplayBus(entries)
+
+	value, err := transcriptSession(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(value, []byte{0x12, 0x34, 0x56}) {
+		t.Errorf("Replayed read returned % x", value)
+	}
+
+	if err := replay.Done(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTranscriptMismatch(t *testing.T) {
+	replay := NewReplayBus(recordTranscript(t))
+
+	if err := Device(replay, transcriptDevice).WriteByteRegister(0x0010, 0x12); !errors.Is(err, ErrReplayMismatch) {
+		t.Errorf("Write not in the transcript returned %v", err)
+	}
+
+	if err := replay.Done(); !errors.Is(err, ErrReplayMismatch) {
+		t.Errorf("Done returned %v after mismatch", err)
+	}
+}
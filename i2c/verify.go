@@ -0,0 +1,104 @@
+package i2c
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVerifyMismatch - value read back from a register is not the value written to it
+var ErrVerifyMismatch = errors.New("register read back mismatch")
+
+// VerifyPolicy - how register writes are verified
+//
+// When a policy is attached to a device (WithVerifyPolicy), each register write is followed by reading the
+// registers back and comparing them to the written value. A mismatch fails the write with I2CdeviceVerifyError.
+// Registers that do not read back the written value (such as self clearing start or interrupt clear registers)
+// should be exempted
+//
+type VerifyPolicy struct {
+	// Exempt - return true if a register is not verified. If nil, all registers are verified
+	Exempt func(register uint16) bool
+}
+
+// I2CdeviceVerifyError - Error returned when a register does not read back the written value
+type I2CdeviceVerifyError struct {
+	I2CdeviceRegisterError
+	Wrote []byte
+	Read  []byte
+}
+
+// Error - return error message
+func (theError I2CdeviceVerifyError) Error() string {
+	return fmt.Sprintf("%s (wrote % x, read % x)", theError.I2CdeviceRegisterError.Error(), theError.Wrote, theError.Read)
+}
+
+// WithVerifyPolicy - Get device object that verifies register writes according to a policy
+func (device I2Cdevice) WithVerifyPolicy(policy *VerifyPolicy) I2Cdevice {
+	device.Verify = policy
+	return device
+}
+
+func (policy *VerifyPolicy) exempt(register uint16) bool {
+	return policy != nil && policy.Exempt != nil && policy.Exempt(register)
+}
+
+// Check if any of the registers written with value should be verified
+func (policy *VerifyPolicy) verifies(register uint16, length int) bool {
+	if policy == nil {
+		return false
+	}
+
+	for i := 0; i < length; i++ {
+		if !policy.exempt(register + uint16(i)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compare the value read back to the written value, skipping exempted registers
+func (device I2Cdevice) verifyError(register uint16, wrote []byte, read []byte) error {
+	for i := range wrote {
+		if wrote[i] != read[i] && !device.Verify.exempt(register+uint16(i)) {
+			return I2CdeviceVerifyError{
+				I2CdeviceRegisterError{I2CdeviceError{device.Address, "Write verify", ErrVerifyMismatch}, register},
+				append([]byte(nil), wrote...),
+				append([]byte(nil), read...),
+			}
+		}
+	}
+
+	return nil
+}
+
+// Read back registers written with value, and check that they hold it
+func (device I2Cdevice) verifyWrite(register uint16, value []byte) error {
+	if !device.Verify.verifies(register, len(value)) {
+		return nil
+	}
+
+	device.Cache = nil // Read the device, not the cached value
+
+	read := make([]byte, len(value))
+	if err := device.readRegister(register, read); err != nil {
+		return err
+	}
+
+	return device.verifyError(register, value, read)
+}
+
+// VerifyRegisters - read registers starting at a given register and check that they hold expected. Returns
+// I2CdeviceVerifyError if they do not. The registers are read from the device (not from the cache), and are
+// checked even if the device verify policy exempts them
+func (device I2Cdevice) VerifyRegisters(register uint16, expected []byte) error {
+	device.Cache = nil
+	device.Verify = nil
+
+	read := make([]byte, len(expected))
+	if err := device.readRegister(register, read); err != nil {
+		return err
+	}
+
+	return device.verifyError(register, expected, read)
+}
@@ -0,0 +1,81 @@
+package tca9548a
+
+import (
+	"sync"
+
+	"github.com/yuvalrakavy/goRaspberryPi/i2c"
+)
+
+// Simulator - simulated TCA9548A on a simulated bus
+//
+// Each downstream channel is a SimBus. Devices attached to the selected channels are reachable from the parent bus
+// (the simulator is attached to it as a bridge), as if the channel switches were closed
+//
+type Simulator struct {
+	mutex    sync.Mutex
+	control  byte
+	channels [Channels]*i2c.SimBus
+}
+
+// NewSimulator - create simulated multiplexer at a given address on a simulated bus, with no channel selected
+func NewSimulator(bus *i2c.SimBus, address byte) *Simulator {
+	sim := &Simulator{}
+
+	for i := range sim.channels {
+		sim.channels[i] = i2c.NewSimBus()
+	}
+
+	bus.Attach(address, sim)
+	bus.AttachBridge(sim)
+	return sim
+}
+
+// Channel - get the simulated bus of a given downstream channel (0...7)
+func (sim *Simulator) Channel(channel int) *i2c.SimBus {
+	return sim.channels[channel]
+}
+
+// Control - get the control register, bit N is set if channel N is selected
+func (sim *Simulator) Control() byte {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	return sim.control
+}
+
+// Write - set the control register, the last byte written is used
+func (sim *Simulator) Write(data []byte) error {
+	if len(data) > 0 {
+		sim.mutex.Lock()
+		sim.control = data[len(data)-1]
+		sim.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// Read - read the control register
+func (sim *Simulator) Read(buffer []byte) error {
+	control := sim.Control()
+
+	for i := range buffer {
+		buffer[i] = control
+	}
+
+	return nil
+}
+
+// Lookup - find device at a given address on the selected channels
+func (sim *Simulator) Lookup(address uint16, tenBit bool) (i2c.SimDevice, bool) {
+	control := sim.Control()
+
+	for i, channel := range sim.channels {
+		if control&(1<<uint(i)) != 0 {
+			if device, found := channel.Lookup(address, tenBit); found {
+				return device, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,184 @@
+package tca9548a
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/yuvalrakavy/goRaspberryPi/i2c"
+)
+
+// Channels - number of downstream channels
+const Channels = 8
+
+// DefaultAddress - mux address with A0..A2 tied low, the address can be set up to 0x77
+const DefaultAddress = 0x70
+
+const noChannel = -1 // selected value when the control register state is not known
+
+// Mux - TCA9548A/PCA9548 I2C multiplexer
+//
+// Each downstream channel is presented as a bus (see Channel). An operation on a channel bus selects the channel
+// before accessing the device, unless it is known to be selected already. The channel selection and the device
+// access are done inside the parent bus Tx, so they are not interleaved with operations on other channels
+//
+type Mux struct {
+	bus      i2c.Bus
+	address  byte
+	mutex    sync.Mutex
+	selected int // Channel selected in the control register, noChannel if not known
+}
+
+// Channel - downstream channel of a multiplexer, implements i2c.Bus
+type Channel struct {
+	mux     *Mux
+	bus     i2c.Bus // The parent bus, or a view of it bounded by a context
+	channel int
+}
+
+// New - get multiplexer at a given address on a bus
+func New(bus i2c.Bus, address byte) *Mux {
+	return &Mux{bus: bus, address: address, selected: noChannel}
+}
+
+// Address - multiplexer address on the parent bus
+func (mux *Mux) Address() byte {
+	return mux.address
+}
+
+// Channel - get bus for a given downstream channel (0...7)
+func (mux *Mux) Channel(channel int) (*Channel, error) {
+	if channel < 0 || channel >= Channels {
+		return nil, i2c.I2CdeviceError{Address: mux.address, Description: fmt.Sprintf("Invalid channel %d (not between 0...%d)", channel, Channels-1)}
+	}
+
+	return &Channel{mux: mux, bus: mux.bus, channel: channel}, nil
+}
+
+// Invalidate - forget the cached channel selection, the channel is selected again on the next operation. Use if the
+// mux may have been reset or accessed by someone else
+func (mux *Mux) Invalidate() {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+
+	mux.selected = noChannel
+}
+
+// Disable - deselect all channels
+func (mux *Mux) Disable() error {
+	return mux.bus.Tx(func(tx i2c.Bus) error {
+		return mux.setControl(tx, 0, noChannel)
+	})
+}
+
+// Selected - read the control register, bit N is set if channel N is selected
+func (mux *Mux) Selected() (byte, error) {
+	control := make([]byte, 1)
+
+	if _, err := mux.bus.Read(mux.address, control); err != nil {
+		return 0, err
+	}
+
+	return control[0], nil
+}
+
+func (mux *Mux) setControl(tx i2c.Bus, control byte, channel int) error {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+
+	if _, err := tx.Write(mux.address, []byte{control}); err != nil {
+		mux.selected = noChannel
+		return err
+	}
+
+	mux.selected = channel
+	return nil
+}
+
+// Select channel, the parent bus Tx must be held
+func (mux *Mux) selectChannel(tx i2c.Bus, channel int) error {
+	mux.mutex.Lock()
+	selected := mux.selected
+	mux.mutex.Unlock()
+
+	if selected == channel {
+		return nil
+	}
+
+	return mux.setControl(tx, 1<<uint(channel), channel)
+}
+
+// Run operation on the channel with the channel selected. The mux itself is reachable from its channels, an
+// operation addressed to it (such as done by a bus scan) may change the selection, so the cached selection is dropped
+func (channel *Channel) selected(addressesMux bool, operation func(tx i2c.Bus) error) error {
+	return channel.bus.Tx(func(tx i2c.Bus) error {
+		if err := channel.mux.selectChannel(tx, channel.channel); err != nil {
+			return err
+		}
+
+		if addressesMux {
+			defer channel.mux.Invalidate()
+		}
+
+		return operation(tx)
+	})
+}
+
+func (channel *Channel) addressesMux(messages []i2c.Message) bool {
+	for _, message := range messages {
+		if message.Flags&i2c.MessageTenBit == 0 && message.Address == uint16(channel.mux.address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Number - the channel number
+func (channel *Channel) Number() int {
+	return channel.channel
+}
+
+// Read - read from the device at a given address on the channel
+func (channel *Channel) Read(address byte, buffer []byte) (n int, err error) {
+	err = channel.selected(address == channel.mux.address, func(tx i2c.Bus) error {
+		n, err = tx.Read(address, buffer)
+		return err
+	})
+
+	return
+}
+
+// Write - write to the device at a given address on the channel
+func (channel *Channel) Write(address byte, buffer []byte) (n int, err error) {
+	err = channel.selected(address == channel.mux.address, func(tx i2c.Bus) error {
+		n, err = tx.Write(address, buffer)
+		return err
+	})
+
+	return
+}
+
+// Transfer - perform messages as one transaction on the channel
+func (channel *Channel) Transfer(messages ...i2c.Message) error {
+	return channel.selected(channel.addressesMux(messages), func(tx i2c.Bus) error {
+		return tx.Transfer(messages...)
+	})
+}
+
+// Tx - run fn with exclusive access to the channel (and the parent bus)
+func (channel *Channel) Tx(fn func(bus i2c.Bus) error) error {
+	return channel.selected(false, func(tx i2c.Bus) error {
+		return fn(&Channel{mux: channel.mux, bus: tx, channel: channel.channel})
+	})
+}
+
+// Close - nothing to release, the parent bus is not closed
+func (channel *Channel) Close() error {
+	return nil
+}
+
+// WithContext - get channel bus whose operations are bounded by ctx (see i2c.WithContext)
+func (channel *Channel) WithContext(ctx context.Context) i2c.Bus {
+	return &Channel{mux: channel.mux, bus: i2c.WithContext(channel.bus, ctx), channel: channel.channel}
+}
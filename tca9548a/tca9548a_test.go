@@ -0,0 +1,208 @@
+package tca9548a
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yuvalrakavy/goRaspberryPi/i2c"
+	"github.com/yuvalrakavy/goRaspberryPi/vl6180x"
+)
+
+// Simulated mux on a bus whose operations are traced
+type simMux struct {
+	sim      *Simulator
+	recorder *i2c.TraceRecorder
+	bus      i2c.Bus
+}
+
+func newSimMux() *simMux {
+	parent := i2c.NewSimBus()
+	mux := &simMux{sim: NewSimulator(parent, DefaultAddress), recorder: &i2c.TraceRecorder{}}
+	mux.bus = i2c.NewTracingBus(parent, mux.recorder)
+
+	return mux
+}
+
+// Values written to the control register since the last call
+func (mux *simMux) controlWrites() []byte {
+	writes := make([]byte, 0, 4)
+
+	for _, event := range mux.recorder.Events() {
+		if event.Address() == DefaultAddress && event.Direction == i2c.TraceWrite && event.Err == nil {
+			// A zero length write (such as done when probing) does not change the control register
+			if data := event.Messages[len(event.Messages)-1].Buffer; len(data) > 0 {
+				writes = append(writes, data[len(data)-1])
+			}
+		}
+	}
+
+	mux.recorder.Reset()
+	return writes
+}
+
+func equalWrites(writes []byte, expected ...byte) bool {
+	if len(writes) != len(expected) {
+		return false
+	}
+
+	for i := range expected {
+		if writes[i] != expected[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Chain of simulated sensors on a channel, returns the functions controlling the first sensor's GPIO0/CE
+func sensorChain(bus *i2c.SimBus, count int) (resetOn func(), resetOff func()) {
+	var first, last *vl6180x.Simulator
+
+	for i := 0; i < count; i++ {
+		sensor := vl6180x.NewSimulator(bus)
+
+		if first == nil {
+			first = sensor
+			first.SetEnabled(false)
+		} else {
+			last.Chain(sensor)
+		}
+
+		last = sensor
+	}
+
+	return func() { first.SetEnabled(false) }, func() { first.SetEnabled(true) }
+}
+
+func TestSensorChains(t *testing.T) {
+	mux := newSimMux()
+	muxDevice := New(mux.bus, DefaultAddress)
+
+	channels := make([]*Channel, 2)
+	for i := range channels {
+		channel, err := muxDevice.Channel(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		channels[i] = channel
+	}
+
+	// The sensors on each channel get the same addresses, they are told apart by the channel
+	for i, channel := range channels {
+		resetOn, resetOff := sensorChain(mux.sim.Channel(i), 2)
+
+		sensors, err := vl6180x.AssignAddresses(channel, 0x40, resetOn, resetOff)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sensors) != 2 || sensors[0].Address != 0x40 || sensors[1].Address != 0x41 {
+			t.Fatalf("Assigned %v on channel %d, expected sensors at 0x40 and 0x41", sensors, i)
+		}
+
+		if writes := mux.controlWrites(); !equalWrites(writes, 1<<uint(i)) {
+			t.Errorf("Control register writes while assigning addresses on channel %d are % x, expected a single select", i, writes)
+		}
+	}
+
+	for i, channel := range channels {
+		sensors, err := vl6180x.ScanBus(channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sensors) != 2 || sensors[0].Address != 0x40 || sensors[1].Address != 0x41 {
+			t.Errorf("ScanBus of channel %d found %v, expected sensors at 0x40 and 0x41", i, sensors)
+		}
+
+		// The scan probes the mux address and then reads its identification register (changing the control register),
+		// the channel is selected again after each of these
+		if writes := mux.controlWrites(); !equalWrites(writes, 1<<uint(i), 1<<uint(i), 1<<uint(i)) {
+			t.Errorf("Control register writes while scanning channel %d are % x, expected three selects", i, writes)
+		}
+
+		if control := mux.sim.Control(); control != 1<<uint(i) {
+			t.Errorf("Control register is %#x after scanning channel %d", control, i)
+		}
+	}
+}
+
+func TestChannelSelection(t *testing.T) {
+	mux := newSimMux()
+
+	for i := 0; i < 2; i++ {
+		device := i2c.NewSimRegisterDevice()
+		device.SetRegister(0, byte(0x10+i))
+		mux.sim.Channel(i).Attach(0x40, device)
+	}
+
+	faults := i2c.NewFaultBus(mux.bus, 1)
+	muxDevice := New(faults, DefaultAddress)
+
+	if _, err := muxDevice.Channel(Channels); err == nil {
+		t.Errorf("Channel(%d) did not fail", Channels)
+	}
+
+	channel0, _ := muxDevice.Channel(0)
+	channel1, _ := muxDevice.Channel(1)
+
+	read := func(channel *Channel) (byte, error) {
+		return i2c.Device(channel, 0x40).ReadByteRegister(0)
+	}
+
+	expectRead := func(channel *Channel) {
+		if value, err := read(channel); err != nil {
+			t.Errorf("Read on channel %d: %v", channel.Number(), err)
+		} else if expected := byte(0x10 + channel.Number()); value != expected {
+			t.Errorf("Read on channel %d returned %#x, expected %#x", channel.Number(), value, expected)
+		}
+	}
+
+	expectRead(channel0)
+	expectRead(channel0)
+
+	if writes := mux.controlWrites(); !equalWrites(writes, 0x01) {
+		t.Errorf("Control register writes for two reads on channel 0 are % x, expected one select", writes)
+	}
+
+	expectRead(channel1)
+	expectRead(channel0)
+
+	if writes := mux.controlWrites(); !equalWrites(writes, 0x02, 0x01) {
+		t.Errorf("Control register writes when switching channels are % x, expected 02 01", writes)
+	}
+
+	if selected, err := muxDevice.Selected(); err != nil || selected != 0x01 {
+		t.Errorf("Selected returned %#x, %v, expected 0x1", selected, err)
+	}
+
+	// Failed select: the device is not accessed and the selection is no longer assumed
+	faults.AddRule(i2c.FaultRule{Kind: i2c.FaultNoAck, Addresses: []uint16{DefaultAddress}, Count: 1})
+
+	if _, err := read(channel1); !errors.Is(err, i2c.ErrNoAck) {
+		t.Errorf("Read with failed select returned %v", err)
+	}
+
+	if control := mux.sim.Control(); control != 0x01 {
+		t.Errorf("Control register changed to %#x by failed select", control)
+	}
+
+	expectRead(channel0)
+
+	if writes := mux.controlWrites(); !equalWrites(writes, 0x01) {
+		t.Errorf("Control register writes after failed select are % x, expected channel 0 to be selected again", writes)
+	}
+
+	if err := muxDevice.Disable(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := read(channel0); err != nil {
+		t.Errorf("Read on channel 0 after Disable: %v", err)
+	}
+
+	if writes := mux.controlWrites(); !equalWrites(writes, 0x00, 0x01) {
+		t.Errorf("Control register writes for Disable and read are % x, expected 00 01", writes)
+	}
+}
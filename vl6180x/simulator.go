@@ -0,0 +1,331 @@
+package vl6180x
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yuvalrakavy/goRaspberryPi/i2c"
+)
+
+const defaultSimulatorConversionTime = 5 * time.Millisecond
+
+// Simulator - behavioral model of a VL6180x attached to a simulated I2C bus
+//
+// The model implements the register semantics used by the driver:
+//
+//   SYSTEM__FRESH_OUT_OF_RESET is 1 after the sensor is taken out of reset
+//   SYSRANGE__START and SYSALS__START start single-shot or continuous measurements, writing the start bit
+//   while continuous mode is active stops it. SYSALS__START starts interleaved measurements when
+//   INTERLEAVED_MODE__ENABLE is set
+//   RESULT__INTERRUPT_STATUS_GPIO reports new range (4) and ambient (4 << 3) samples until they are cleared
+//   by SYSTEM__INTERRUPT_CLEAR
+//   Writing I2C_SLAVE__DEVICE_ADDRESS moves the sensor to the new address on the bus
+//   GPIO1 drives the GPIO0/CE input of the next sensor in the chain (see Chain)
+//
+// Measurements are evaluated when result registers are read, based on the time elapsed since they were
+// started. The measured distance is taken from the Distance function and the ambient light from the
+// Ambient function
+//
+type Simulator struct {
+	*i2c.SimRegisterDevice
+
+	// Distance - target distance in mm as function of the time since the simulator was created
+	Distance func(elapsed time.Duration) byte
+
+	// Ambient - ambient light reading as function of the time since the simulator was created
+	Ambient func(elapsed time.Duration) uint16
+
+	// ConversionTime - time it takes to complete a single measurement
+	ConversionTime time.Duration
+
+	mutex   sync.Mutex
+	bus     *i2c.SimBus
+	created time.Time
+	address byte
+	enabled bool
+	next    *Simulator
+	ranging simMeasurement
+	ambient simMeasurement
+}
+
+type simMeasurement struct {
+	running    bool
+	continuous bool
+	due        time.Time // Completion time of the next sample
+}
+
+// DistanceStep - target distance from a given time on
+type DistanceStep struct {
+	At       time.Duration
+	Distance byte
+}
+
+// DistanceScript - return a Distance function that follows a script of steps. Steps must be ordered by time,
+// the distance before the first step is the distance of the first step
+func DistanceScript(steps ...DistanceStep) func(elapsed time.Duration) byte {
+	return func(elapsed time.Duration) byte {
+		var distance byte
+
+		for i, step := range steps {
+			if i == 0 || step.At <= elapsed {
+				distance = step.Distance
+			}
+		}
+
+		return distance
+	}
+}
+
+// NewSimulator - create a simulated VL6180x on a simulated bus. The sensor is out of reset, at the default address
+func NewSimulator(bus *i2c.SimBus) *Simulator {
+	sim := &Simulator{
+		SimRegisterDevice: i2c.NewSimRegisterDevice(),
+		Distance:          func(time.Duration) byte { return 255 },
+		Ambient:           func(time.Duration) uint16 { return 0 },
+		ConversionTime:    defaultSimulatorConversionTime,
+		bus:               bus,
+		created:           time.Now(),
+	}
+
+	sim.OnRead = sim.onRead
+	sim.OnWrite = sim.onWrite
+
+	sim.SetEnabled(true)
+	return sim
+}
+
+// Address - current bus address of the simulated sensor
+func (sim *Simulator) Address() byte {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	return sim.address
+}
+
+// Enabled - return the state of the GPIO0/CE input
+func (sim *Simulator) Enabled() bool {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	return sim.enabled
+}
+
+// SetEnabled - set the GPIO0/CE input. When low the sensor is in reset and does not respond on the bus. When
+// taken out of reset, all registers are set to their power-up values and the sensor responds at the default address
+func (sim *Simulator) SetEnabled(enabled bool) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	if enabled == sim.enabled {
+		return
+	}
+
+	sim.enabled = enabled
+
+	if enabled {
+		sim.powerUp()
+		sim.address = defaultVl6180xAddress
+		sim.bus.Attach(sim.address, sim)
+	} else {
+		sim.bus.Detach(sim.address)
+		sim.ranging = simMeasurement{}
+		sim.ambient = simMeasurement{}
+	}
+
+	sim.driveGPIO1()
+}
+
+// Chain - connect this sensor's GPIO1 output to the GPIO0/CE input of the next sensor
+func (sim *Simulator) Chain(next *Simulator) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.next = next
+	sim.driveGPIO1()
+}
+
+// Set all registers to their power-up values (see Registers)
+func (sim *Simulator) powerUp() {
+	sim.Reset()
+
+	for _, register := range Registers {
+		width := register.Width
+		if width == 0 {
+			width = i2c.Value8
+		}
+
+		value := make([]byte, width)
+		for i := range value {
+			value[len(value)-1-i] = byte(register.Reset >> uint(8*i))
+		}
+
+		sim.SetRegisters(register.Address, value...)
+	}
+}
+
+// GPIO1 level: in reset the output is low. With the output function turned off (select = 0) the pin is
+// released and pulled up, unless active high polarity is selected (the power-up value) which keeps the next
+// sensor in reset. As interrupt output (select = 8) the pin reflects pending interrupts.
+func (sim *Simulator) gpio1Level() bool {
+	if !sim.enabled {
+		return false
+	}
+
+	mode := sim.Register(registerSystemModeGpio1)
+	activeHigh := mode&0x20 != 0
+
+	switch (mode >> 1) & 0x0f {
+	case 0:
+		return !activeHigh
+	case 8:
+		pending := sim.Register(registerResultInterruptStatusGpio)&0x3f != 0
+		return pending == activeHigh
+	default:
+		return false
+	}
+}
+
+func (sim *Simulator) driveGPIO1() {
+	if sim.next != nil {
+		sim.next.SetEnabled(sim.gpio1Level())
+	}
+}
+
+func (sim *Simulator) elapsed(now time.Time) time.Duration {
+	return now.Sub(sim.created)
+}
+
+func (sim *Simulator) period(register uint16) time.Duration {
+	return time.Duration(sim.Register(register)+1) * 10 * time.Millisecond
+}
+
+func (sim *Simulator) scale() byte {
+	switch sim.Register(registerRangeScaler + 1) {
+	case 127:
+		return 2
+	case 84:
+		return 3
+	default:
+		return 1
+	}
+}
+
+func (sim *Simulator) completeRange(now time.Time) {
+	sim.SetRegister(registerResultRangeVal, sim.Distance(sim.elapsed(now))/sim.scale())
+	status := sim.Register(registerResultInterruptStatusGpio)
+	sim.SetRegister(registerResultInterruptStatusGpio, (status&^0x07)|0x04)
+}
+
+func (sim *Simulator) completeAmbient(now time.Time) {
+	value := sim.Ambient(sim.elapsed(now))
+	sim.SetRegisters(registerResultAlsVal, byte(value>>8), byte(value))
+	status := sim.Register(registerResultInterruptStatusGpio)
+	sim.SetRegister(registerResultInterruptStatusGpio, (status&^0x38)|0x20)
+}
+
+// Complete all measurements that are due by now
+func (sim *Simulator) update(now time.Time) {
+	interleaved := sim.Register(registerInterleavedModeEnable)&0x01 != 0
+
+	for sim.ranging.running && !now.Before(sim.ranging.due) {
+		sim.completeRange(sim.ranging.due)
+
+		if sim.ranging.continuous {
+			sim.ranging.due = sim.ranging.due.Add(sim.period(registerSysrangeIntermeasurementPeriod))
+		} else {
+			sim.ranging.running = false
+		}
+	}
+
+	for sim.ambient.running && !now.Before(sim.ambient.due) {
+		sim.completeAmbient(sim.ambient.due)
+
+		if interleaved {
+			sim.completeRange(sim.ambient.due)
+		}
+
+		if sim.ambient.continuous {
+			sim.ambient.due = sim.ambient.due.Add(sim.period(registerSysalsIntermeasurementPeriod))
+		} else {
+			sim.ambient.running = false
+		}
+	}
+
+	sim.driveGPIO1()
+}
+
+// Handle write of SYSRANGE__START or SYSALS__START
+func (sim *Simulator) start(measurement *simMeasurement, value byte) {
+	if value&0x01 == 0 {
+		return
+	}
+
+	if measurement.running && measurement.continuous {
+		measurement.running = false
+		return
+	}
+
+	*measurement = simMeasurement{
+		running:    true,
+		continuous: value&0x02 != 0,
+		due:        time.Now().Add(sim.ConversionTime),
+	}
+}
+
+func (sim *Simulator) onWrite(register uint16, value byte) byte {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.update(time.Now())
+
+	switch register {
+	case registerSysrangeStart:
+		sim.start(&sim.ranging, value)
+		return value &^ 0x01
+
+	case registerSysalsStart:
+		sim.start(&sim.ambient, value)
+		return value &^ 0x01
+
+	case registerSystemInterruptClear:
+		var mask byte
+
+		if value&0x01 != 0 {
+			mask |= 0x07
+		}
+		if value&0x02 != 0 {
+			mask |= 0x38
+		}
+		if value&0x04 != 0 {
+			mask |= 0xc0
+		}
+
+		sim.SetRegister(registerResultInterruptStatusGpio, sim.Register(registerResultInterruptStatusGpio)&^mask)
+		sim.driveGPIO1()
+		return 0
+
+	case registerSystemModeGpio1:
+		sim.SetRegister(registerSystemModeGpio1, value)
+		sim.driveGPIO1()
+
+	case registerI2CSlaveDeviceAddress:
+		value &= 0x7f
+		sim.bus.Detach(sim.address)
+		sim.address = value
+		sim.bus.Attach(sim.address, sim)
+	}
+
+	return value
+}
+
+func (sim *Simulator) onRead(register uint16, value byte) byte {
+	if register < registerResultRangeStatus || register > registerResultRangeReferenceConvTime+3 {
+		return value
+	}
+
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.update(time.Now())
+	return sim.Register(register)
+}
@@ -0,0 +1,442 @@
+package vl6180x
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yuvalrakavy/goPool"
+	"github.com/yuvalrakavy/goRaspberryPi/i2c"
+)
+
+// Chain of simulated sensors, the first sensor's GPIO0/CE is driven by the reset functions
+type simChain struct {
+	bus     *i2c.SimBus
+	sensors []*Simulator
+}
+
+func newSimChain(count int) *simChain {
+	chain := &simChain{bus: i2c.NewSimBus()}
+
+	for i := 0; i < count; i++ {
+		sensor := NewSimulator(chain.bus)
+
+		if i == 0 {
+			sensor.SetEnabled(false)
+		} else {
+			chain.sensors[i-1].Chain(sensor)
+		}
+
+		chain.sensors = append(chain.sensors, sensor)
+	}
+
+	return chain
+}
+
+func (chain *simChain) resetOn()  { chain.sensors[0].SetEnabled(false) }
+func (chain *simChain) resetOff() { chain.sensors[0].SetEnabled(true) }
+
+// Brown-out: all the sensors are reset, only the first one comes back
+func (chain *simChain) brownOut() {
+	chain.resetOn()
+	chain.resetOff()
+}
+
+func cachedDevice(bus i2c.Bus, address byte) Vl6180x {
+	return Device(bus, address).WithCache()
+}
+
+func TestResyncAfterBrownOut(t *testing.T) {
+	chain := newSimChain(2)
+
+	sensors, err := AssignAddressesUsing(chain.bus, 42, chain.resetOn, chain.resetOff, cachedDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sensors) != 2 {
+		t.Fatalf("Assigned %d sensors, expected 2", len(sensors))
+	}
+
+	for _, sensor := range sensors {
+		if err := IsVL6180x(chain.bus, sensor.Address); err != nil {
+			t.Errorf("Sensor at %d not identified after Initialize: %v", sensor.Address, err)
+		}
+	}
+
+	if reset, err := sensors.Resync(); err != nil || reset {
+		t.Fatalf("Resync without reset returned %v, %v", reset, err)
+	}
+
+	for _, sensor := range sensors {
+		if err := IsVL6180x(chain.bus, sensor.Address); err != nil {
+			t.Errorf("Sensor at %d not identified after Resync: %v", sensor.Address, err)
+		}
+	}
+
+	found, err := ScanBus(chain.bus)
+	if err != nil || len(found) != 2 {
+		t.Fatalf("ScanBus found %d sensors (%v), expected 2", len(found), err)
+	}
+
+	chain.brownOut()
+
+	if address := chain.sensors[0].Address(); address != defaultVl6180xAddress {
+		t.Fatalf("Reset sensor is at %d, expected the default address", address)
+	}
+
+	if chain.sensors[1].Enabled() {
+		t.Fatal("Second sensor is out of reset after brown-out")
+	}
+
+	if reset, err := sensors.Resync(); err != nil || !reset {
+		t.Fatalf("Resync after brown-out returned %v, %v", reset, err)
+	}
+
+	for i, sim := range chain.sensors {
+		if sim.Address() != sensors[i].Address {
+			t.Errorf("Sensor %d is at %d, expected %d", i, sim.Address(), sensors[i].Address)
+		}
+
+		if value := sim.Register(0x0207); value != 0x01 {
+			t.Errorf("Sensor %d configuration not restored (register 0x207 is %#x)", i, value)
+		}
+
+		if value := sim.Register(registerSystemFreshOutOfReset); value != 0 {
+			t.Errorf("Sensor %d SYSTEM__FRESH_OUT_OF_RESET is %d after Resync", i, value)
+		}
+	}
+
+	if reset, err := sensors.Resync(); err != nil || reset {
+		t.Fatalf("Second Resync returned %v, %v", reset, err)
+	}
+
+	for _, sensor := range sensors {
+		if _, err := sensor.ReadRange(1000); err != nil {
+			t.Errorf("ReadRange of sensor at %d after Resync: %v", sensor.Address, err)
+		}
+	}
+}
+
+func TestAssignAddresses(t *testing.T) {
+	chain := newSimChain(3)
+
+	sensors, err := AssignAddresses(chain.bus, 50, chain.resetOn, chain.resetOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sensors) != len(chain.sensors) {
+		t.Fatalf("Assigned %d sensors, expected %d", len(sensors), len(chain.sensors))
+	}
+
+	for i, sim := range chain.sensors {
+		if expected := byte(50 + i); sensors[i].Address != expected || sim.Address() != expected {
+			t.Errorf("Sensor %d is at %d (simulator at %d), expected %d", i, sensors[i].Address, sim.Address(), expected)
+		}
+	}
+
+	if _, attached := chain.bus.Attached(defaultVl6180xAddress); attached {
+		t.Error("A sensor is left at the default address")
+	}
+}
+
+func TestReadRange(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+	chain.sensors[0].Distance = DistanceScript(DistanceStep{Distance: 120})
+
+	sensor := Device(chain.bus, defaultVl6180xAddress)
+	if err := sensor.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	distance, err := sensor.ReadRange(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if distance != 120 {
+		t.Errorf("ReadRange returned %d, expected 120", distance)
+	}
+
+	if err := sensor.SetScaling(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if distance, err = sensor.ReadRange(1000); err != nil || distance != 60 {
+		t.Errorf("ReadRange with 2x scaling returned %d, %v, expected 60", distance, err)
+	}
+}
+
+func TestReadRangeTimeout(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+	chain.sensors[0].ConversionTime = time.Second
+
+	sensor := Device(chain.bus, defaultVl6180xAddress)
+	if err := sensor.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sensor.ReadRange(20); !errors.As(err, &Timeout{}) {
+		t.Errorf("ReadRange returned %v, expected timeout", err)
+	}
+}
+
+func TestReadAmbient(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+	chain.sensors[0].Ambient = func(time.Duration) uint16 { return 0x1234 }
+
+	sensor := Device(chain.bus, defaultVl6180xAddress)
+	if err := sensor.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	ambient, err := sensor.ReadAmbient(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ambient != 0x1234 {
+		t.Errorf("ReadAmbient returned %#x, expected 0x1234", ambient)
+	}
+}
+
+func TestScanBusSkipsWriteSensitiveAddresses(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+
+	eeprom := i2c.NewSimRegisterDevice()
+	eeprom.SetRegister(0, 0xa5)
+	chain.bus.Attach(0x50, eeprom)
+
+	var recorder i2c.TraceRecorder
+	sensors, err := ScanBus(i2c.NewTracingBus(chain.bus, &recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sensors) != 1 || sensors[0].Address != defaultVl6180xAddress {
+		t.Errorf("ScanBus found %v, expected the sensor at the default address", sensors)
+	}
+
+	for _, event := range recorder.Events() {
+		if event.Address() == 0x50 && event.Direction == i2c.TraceWrite {
+			t.Errorf("ScanBus wrote to the EEPROM address: %v", event.Messages)
+		}
+	}
+
+	if value := eeprom.Register(0); value != 0xa5 {
+		t.Errorf("EEPROM byte 0 changed to %#x", value)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	chain := newSimChain(2)
+
+	if _, err := AssignAddresses(chain.bus, 0x2a, chain.resetOn, chain.resetOff); err != nil {
+		t.Fatal(err)
+	}
+
+	other := i2c.NewSimRegisterDevice()
+	other.Address8 = true
+	other.SetRegister(0, 0x5a)
+	chain.bus.Attach(0x40, other)
+
+	drivers := func() map[byte]string {
+		matches, err := i2c.Identify(chain.bus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := make(map[byte]string)
+		for _, match := range matches {
+			found[match.Address] = match.Driver
+		}
+
+		return found
+	}
+
+	if found := drivers(); found[0x2a] != "" || found[0x2b] != "" || found[0x40] != "" {
+		t.Errorf("Identified %v before the sensor addresses were added", found)
+	}
+
+	AddProbeAddresses(0x2a, 0x2b)
+
+	if found := drivers(); found[0x2a] != probeDriver || found[0x2b] != probeDriver || found[0x40] != "" {
+		t.Errorf("Identified %v, expected sensors at 0x2a and 0x2b", found)
+	}
+
+	if value := other.Register(0); value != 0x5a {
+		t.Errorf("Register 0 of device with 8 bit register addresses changed to %#x", value)
+	}
+}
+
+// SetScaling keeps the baseline update of SYSRANGE__RANGE_CHECK_ENABLES: the upper nibble is cleared, and the
+// early convergence enable bit is set for 1x scaling (and left as is otherwise)
+func TestSetScalingRangeCheckEnables(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+	sim := chain.sensors[0]
+
+	sensor := Device(chain.bus, defaultVl6180xAddress)
+	if err := sensor.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		before   byte
+		scale    byte
+		expected byte
+	}{
+		{0x11, 1, 0x01},
+		{0xf0, 2, 0x00},
+		{0xf0, 1, 0x01},
+		{0x01, 2, 0x01},
+		{0x1e, 3, 0x0e},
+	}
+
+	for _, step := range steps {
+		sim.SetRegister(registerSysrangeRangeCheckEnables, step.before)
+
+		if err := sensor.SetScaling(step.scale); err != nil {
+			t.Fatal(err)
+		}
+
+		if value := sim.Register(registerSysrangeRangeCheckEnables); value != step.expected {
+			t.Errorf("SetScaling(%d) with %#02x set SYSRANGE__RANGE_CHECK_ENABLES to %#02x, expected %#02x", step.scale, step.before, value, step.expected)
+		}
+	}
+}
+
+func TestReadRangeWithRetries(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+	chain.sensors[0].Distance = DistanceScript(DistanceStep{Distance: 120})
+
+	faults := i2c.NewFaultBus(chain.bus, 42)
+	policy := &i2c.RetryPolicy{Attempts: 10, Backoff: time.Millisecond, RetryWrites: true}
+	sensor := Device(faults, defaultVl6180xAddress).WithRetryPolicy(policy)
+
+	if err := sensor.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	faults.AddRule(i2c.FaultRule{Kind: i2c.FaultNoAck, Probability: 0.3})
+
+	for i := 0; i < 20; i++ {
+		if distance, err := sensor.ReadRange(1000); err != nil || distance != 120 {
+			t.Fatalf("ReadRange %d returned %d, %v, expected 120", i, distance, err)
+		}
+	}
+
+	if faults.Injected() == 0 || policy.Retries() != uint64(faults.Injected()) || policy.Failures() != 0 {
+		t.Errorf("Injected %d faults, %d retries and %d failures", faults.Injected(), policy.Retries(), policy.Failures())
+	}
+}
+
+func TestVerifyBitFlip(t *testing.T) {
+	chain := newSimChain(1)
+	chain.resetOff()
+
+	faults := i2c.NewFaultBus(chain.bus, 1)
+	sensor := Device(faults, defaultVl6180xAddress).WithVerify()
+
+	if err := sensor.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	faults.AddRule(i2c.FaultRule{Kind: i2c.FaultBitFlip, Registers: []uint16{registerRangeScaler}, Count: 1})
+
+	var verifyError i2c.I2CdeviceVerifyError
+	if err := sensor.SetScaling(2); !errors.Is(err, i2c.ErrVerifyMismatch) || !errors.As(err, &verifyError) {
+		t.Fatalf("SetScaling with corrupted write returned %v", err)
+	}
+
+	if verifyError.Register != registerRangeScaler {
+		t.Errorf("Verify failed for register %#x, expected RANGE_SCALER", verifyError.Register)
+	}
+
+	if err := sensor.SetScaling(2); err != nil {
+		t.Errorf("SetScaling after the fault: %v", err)
+	}
+}
+
+// Distance script whose step times are relative to now rather than to the creation of the simulator
+func scriptFromNow(sim *Simulator, steps ...DistanceStep) func(elapsed time.Duration) byte {
+	start := time.Since(sim.created)
+	script := DistanceScript(steps...)
+
+	return func(elapsed time.Duration) byte { return script(elapsed - start) }
+}
+
+func TestGetRangeReadingChannel(t *testing.T) {
+	chain := newSimChain(2)
+
+	sensors, err := AssignAddresses(chain.bus, 0x40, chain.resetOn, chain.resetOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain.sensors[0].Distance = scriptFromNow(chain.sensors[0],
+		DistanceStep{Distance: 100}, DistanceStep{At: 300 * time.Millisecond, Distance: 80}, DistanceStep{At: 600 * time.Millisecond, Distance: 60})
+	chain.sensors[1].Distance = scriptFromNow(chain.sensors[1],
+		DistanceStep{Distance: 200}, DistanceStep{At: 450 * time.Millisecond, Distance: 150})
+
+	expected := map[byte][]byte{0x40: {100, 80, 60}, 0x41: {200, 150}}
+	readings := make(map[byte][]byte)
+
+	pool, values := sensors.GetRangeReadingChannel(goPool.Make())
+	timeout := time.After(2 * time.Second)
+
+	for len(readings[0x40]) < len(expected[0x40]) || len(readings[0x41]) < len(expected[0x41]) {
+		select {
+		case message := <-values:
+			readings[message.Sensor.Address] = append(readings[message.Sensor.Address], message.Distance)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for readings, got %v", readings)
+		}
+	}
+
+	for address, distances := range expected {
+		if string(readings[address]) != string(distances) {
+			t.Errorf("Sensor at %#x readings are %v, expected %v", address, readings[address], distances)
+		}
+	}
+
+	// The reading goroutine may be blocked sending a reading, so the channel is drained while terminating
+	terminated := make(chan struct{})
+	go func() {
+		pool.Terminate()
+		close(terminated)
+	}()
+
+	timeout = time.After(time.Second)
+
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-values:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("Channel not closed after the pool was terminated")
+		}
+	}
+
+	select {
+	case <-terminated:
+	case <-timeout:
+		t.Fatal("Pool termination did not complete")
+	}
+
+	for i, sim := range chain.sensors {
+		sim.mutex.Lock()
+		running := sim.ranging.running
+		sim.mutex.Unlock()
+
+		if running {
+			t.Errorf("Sensor %d is still in continuous ranging mode", i)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package vl6180x
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"time"
 
@@ -82,6 +85,102 @@ const (
 	registerInterleavedModeEnable        = 0x2A3
 )
 
+// Registers - VL6180x register definitions, with register names as used in the datasheet and power-up values
+var Registers = i2c.RegisterMap{
+	{Name: "IDENTIFICATION__MODEL_ID", Address: registerIdentificationModelID, Access: i2c.ReadOnly, Reset: vl6180xModelID},
+	{Name: "IDENTIFICATION__MODEL_REV_MAJOR", Address: registerIdentificationModelRevMajor, Access: i2c.ReadOnly, Reset: vl6180xModelRevMajor},
+	{Name: "IDENTIFICATION__MODEL_REV_MINOR", Address: registerIdentificationModelRevMinor, Access: i2c.ReadOnly, Reset: 0x03},
+	{Name: "IDENTIFICATION__MODULE_REV_MAJOR", Address: registerIdentificationModuleRevMajor, Access: i2c.ReadOnly, Reset: 0x01},
+	{Name: "IDENTIFICATION__MODULE_REV_MINOR", Address: registerIdentificationModuleRevMinor, Access: i2c.ReadOnly, Reset: 0x02},
+	{Name: "IDENTIFICATION__DATE_HI", Address: registerIdentificationDateHi, Access: i2c.ReadOnly},
+	{Name: "IDENTIFICATION__DATE_LO", Address: registerIdentificationDateLo, Access: i2c.ReadOnly},
+	{Name: "IDENTIFICATION__TIME", Address: registerIdentificationTime, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "SYSTEM__MODE_GPIO0", Address: registerSystemModeGpio0, Reset: 0x60},
+	{Name: "SYSTEM__MODE_GPIO1", Address: registerSystemModeGpio1, Reset: 0x20},
+	{Name: "SYSTEM__HISTORY_CTRL", Address: registerSystemHistoryCtrl},
+	{Name: "SYSTEM__INTERRUPT_CONFIG_GPIO", Address: registerSystemInterruptConfigGpio},
+	{Name: "SYSTEM__INTERRUPT_CLEAR", Address: registerSystemInterruptClear},
+	{Name: "SYSTEM__FRESH_OUT_OF_RESET", Address: registerSystemFreshOutOfReset, Reset: 0x01},
+	{Name: "SYSTEM__GROUPED_PARAMETER_HOLD", Address: registerSystemGroupedParameterHold},
+	{Name: "SYSRANGE__START", Address: registerSysrangeStart},
+	{Name: "SYSRANGE__THRESH_HIGH", Address: registerSysrangeThreshHigh},
+	{Name: "SYSRANGE__THRESH_LOW", Address: registerSysrangeThreshLow},
+	{Name: "SYSRANGE__INTERMEASUREMENT_PERIOD", Address: registerSysrangeIntermeasurementPeriod, Reset: 0xff},
+	{Name: "SYSRANGE__MAX_CONVERGENCE_TIME", Address: registerSysrangeMaxConvergenceTime},
+	{Name: "SYSRANGE__CROSSTALK_COMPENSATION_RATE", Address: registerSysrangeCrosstalkCompensationRate, Width: i2c.Value16},
+	{Name: "SYSRANGE__CROSSTALK_VALID_HEIGHT", Address: registerSysrangeCrosstalkValidHeight},
+	{Name: "SYSRANGE__EARLY_CONVERGENCE_ESTIMATE", Address: registerSysrangeEarlyConvergenceEstimate, Width: i2c.Value16},
+	{Name: "SYSRANGE__PART_TO_PART_RANGE_OFFSET", Address: registerSysrangePartToPartRangeOffset},
+	{Name: "SYSRANGE__RANGE_IGNORE_VALID_HEIGHT", Address: registerSysrangeRangeIgnoreValidHeight},
+	{Name: "SYSRANGE__RANGE_IGNORE_THRESHOLD", Address: registerSysrangeRangeIgnoreThreshold, Width: i2c.Value16},
+	{Name: "SYSRANGE__MAX_AMBIENT_LEVEL_MULT", Address: registerSysrangeMaxAmbientLevelMult},
+	{Name: "SYSRANGE__RANGE_CHECK_ENABLES", Address: registerSysrangeRangeCheckEnables, Reset: 0x11},
+	{Name: "SYSRANGE__VHV_RECALIBRATE", Address: registerSysrangeVhvRecalibrate},
+	{Name: "SYSRANGE__VHV_REPEAT_RATE", Address: registerSysrangeVhvRepeatRate},
+	{Name: "SYSALS__START", Address: registerSysalsStart},
+	{Name: "SYSALS__THRESH_HIGH", Address: registerSysalsThreshHigh},
+	{Name: "SYSALS__THRESH_LOW", Address: registerSysalsThreshLow},
+	{Name: "SYSALS__INTERMEASUREMENT_PERIOD", Address: registerSysalsIntermeasurementPeriod, Reset: 0xff},
+	{Name: "SYSALS__ANALOGUE_GAIN", Address: registerSysalsAnalogueGain},
+	{Name: "SYSALS__INTEGRATION_PERIOD", Address: registerSysalsIntegrationPeriod},
+	{Name: "RESULT__RANGE_STATUS", Address: registerResultRangeStatus, Access: i2c.ReadOnly, Reset: 0x01},
+	{Name: "RESULT__ALS_STATUS", Address: registerResultAlsStatus, Access: i2c.ReadOnly},
+	{Name: "RESULT__INTERRUPT_STATUS_GPIO", Address: registerResultInterruptStatusGpio, Access: i2c.ReadOnly},
+	{Name: "RESULT__ALS_VAL", Address: registerResultAlsVal, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_0", Address: registerResultHistoryBuffer0, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_1", Address: registerResultHistoryBuffer1, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_2", Address: registerResultHistoryBuffer2, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_3", Address: registerResultHistoryBuffer3, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_4", Address: registerResultHistoryBuffer4, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_5", Address: registerResultHistoryBuffer5, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_6", Address: registerResultHistoryBuffer6, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__HISTORY_BUFFER_7", Address: registerResultHistoryBuffer7, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_VAL", Address: registerResultRangeVal, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_RAW", Address: registerResultRangeRaw, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_RETURN_RATE", Address: registerResultRangeReturnRate, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_REFERENCE_RATE", Address: registerResultRangeReferenceRate, Width: i2c.Value16, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_RETURN_SIGNAL_COUNT", Address: registerResultRangeReturnSignalCount, Width: i2c.Value32, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_REFERENCE_SIGNAL_COUNT", Address: registerResultRangeReferenceSignalCount, Width: i2c.Value32, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_RETURN_AMB_COUNT", Address: registerResultRangeReturnAmbCount, Width: i2c.Value32, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_REFERENCE_AMB_COUNT", Address: registerResultRangeReferenceAmbCount, Width: i2c.Value32, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_RETURN_CONV_TIME", Address: registerResultRangeReturnConvTime, Width: i2c.Value32, Access: i2c.ReadOnly},
+	{Name: "RESULT__RANGE_REFERENCE_CONV_TIME", Address: registerResultRangeReferenceConvTime, Width: i2c.Value32, Access: i2c.ReadOnly},
+	{Name: "RANGE_SCALER", Address: registerRangeScaler, Width: i2c.Value16, Reset: 0x00fd},
+	{Name: "READOUT__AVERAGING_SAMPLE_PERIOD", Address: registerReadoutAveragingSamplePeriod},
+	{Name: "FIRMWARE__BOOTUP", Address: registerFirmwareBootup},
+	{Name: "FIRMWARE__RESULT_SCALER", Address: registerFirmwareResultScaler},
+	{Name: "I2C_SLAVE__DEVICE_ADDRESS", Address: registerI2CSlaveDeviceAddress, Reset: defaultVl6180xAddress},
+	{Name: "INTERLEAVED_MODE__ENABLE", Address: registerInterleavedModeEnable},
+}
+
+// RegisterNames - VL6180x register names, for annotating bus traces (see i2c.TraceDecoder)
+var RegisterNames = Registers.Names()
+
+// Register and field definitions used for read-modify-write and composing register values
+var (
+	systemModeGpio1 = lookupRegister(registerSystemModeGpio1)
+	gpio1Polarity   = i2c.Field{Register: systemModeGpio1, Name: "polarity", Shift: 5, Bits: 1} // 1 - active high
+	gpio1Select     = i2c.Field{Register: systemModeGpio1, Name: "select", Shift: 1, Bits: 4}   // Pin function
+
+	sysrangeRangeCheckEnables = lookupRegister(registerSysrangeRangeCheckEnables)
+	earlyConvergenceEnable    = i2c.Field{Register: sysrangeRangeCheckEnables, Name: "early_convergence_enable", Shift: 0, Bits: 1}
+)
+
+func lookupRegister(address uint16) i2c.Register {
+	register, found := Registers.Lookup(address)
+	if !found {
+		panic(fmt.Sprintf("vl6180x: register %#03x is not defined", address))
+	}
+
+	return register
+}
+
+// GPIO1 pin functions (gpio1Select values)
+const (
+	gpio1SelectOff       = 0
+	gpio1SelectInterrupt = 8
+)
+
 // Vl6180x - ST Electronics time of flight sensor
 type Vl6180x struct {
 	i2c.I2Cdevice
@@ -92,6 +191,16 @@ type Timeout struct {
 	i2c.I2CdeviceError
 }
 
+// RangeStatistics - ranging counters of the last range measurement
+type RangeStatistics struct {
+	ReturnSignalCount        uint32
+	ReferenceSignalCount     uint32
+	ReturnAmbientCount       uint32
+	ReferenceAmbientCount    uint32
+	ReturnConvergenceTime    uint32
+	ReferenceConvergenceTime uint32
+}
+
 type Vl6180identification struct {
 	Model          byte
 	ModelRevMajor  byte
@@ -103,40 +212,172 @@ type Vl6180identification struct {
 }
 
 // Device - get Vl6180x device at a given address
-func Device(bus *i2c.I2Cbus, address byte) Vl6180x {
-	return Vl6180x{bus.Device(address)}
+func Device(bus i2c.Bus, address byte) Vl6180x {
+	return Vl6180x{i2c.Device(bus, address)}
+}
+
+// WithRetryPolicy - get sensor object that retries failed register access according to a policy
+func (device Vl6180x) WithRetryPolicy(policy *i2c.RetryPolicy) Vl6180x {
+	return Vl6180x{device.I2Cdevice.WithRetryPolicy(policy)}
+}
+
+// Registers that are changed by the sensor, or whose writes trigger actions, are not cached
+func isVolatileRegister(register uint16) bool {
+	switch register {
+	case registerSystemInterruptClear, registerSystemFreshOutOfReset, registerSystemGroupedParameterHold,
+		registerSysrangeStart, registerSysrangeVhvRecalibrate, registerSysalsStart,
+		registerFirmwareBootup, registerI2CSlaveDeviceAddress:
+		return true
+	}
+
+	return register >= registerResultRangeStatus && register < registerResultRangeReferenceConvTime+4
+}
+
+// WithCache - get sensor object that caches the sensor configuration registers. Configuration calls then read
+// registers from memory, and the configuration can be restored after the sensor is reset (see Resync)
+func (device Vl6180x) WithCache() Vl6180x {
+	return Vl6180x{device.I2Cdevice.WithCache(i2c.NewRegisterCache(isVolatileRegister))}
+}
+
+// Registers that do not read back the written value are not verified
+func isVerifyExemptRegister(register uint16) bool {
+	switch register {
+	case registerSystemInterruptClear, registerSysrangeStart, registerSysrangeVhvRecalibrate, registerSysalsStart,
+		registerI2CSlaveDeviceAddress:
+		return true
+	}
+
+	return false
 }
 
-// IsVL6180x return true if the device at a given I2C bus address is a VL6180x
-func IsVL6180x(bus *i2c.I2Cbus, address byte) error {
+// WithVerify - get sensor object that reads back written registers and fails the write if they do not hold the
+// written value. SetAddress then also checks that the sensor answers at its new address
+func (device Vl6180x) WithVerify() Vl6180x {
+	return Vl6180x{device.I2Cdevice.WithVerifyPolicy(&i2c.VerifyPolicy{Exempt: isVerifyExemptRegister})}
+}
+
+// Resync - check if the sensor was reset since it was initialized (or since the last Resync), and if so write
+// back the cached configuration. Returns true if the sensor was reset.
+//
+// A reset sensor answers at the default address. If the sensor does not acknowledge its address, and a sensor
+// that is fresh out of reset is found at the default address, that sensor is moved back to the device address
+// before the configuration is written. Only one sensor can be at the default address, so sensors that were reset
+// together must be resynced one at a time (see Vl6180xGroup.Resync)
+func (device *Vl6180x) Resync() (bool, error) {
+	if device.Cache == nil {
+		return false, i2c.I2CdeviceError{Address: device.Address, Description: "Resync requires register cache (see WithCache)"}
+	}
+
+	freshOutOfReset, err := device.ReadByteRegister(registerSystemFreshOutOfReset)
+	if err != nil {
+		if !errors.Is(err, i2c.ErrNoAck) || device.Address == defaultVl6180xAddress || !device.isResetAtDefaultAddress() {
+			return false, err
+		}
+
+		address := device.Address
+		device.Address = defaultVl6180xAddress
+
+		if err := device.SetAddress(address); err != nil {
+			device.Address = address
+			return true, err
+		}
+
+		freshOutOfReset = 1
+	}
+
+	if freshOutOfReset == 0 {
+		return false, nil
+	}
+
+	device.Cache.MarkDirty()
+	if err := device.SyncCache(); err != nil {
+		return true, err
+	}
+
+	return true, device.WriteByteRegister(registerSystemFreshOutOfReset, 0)
+}
+
+// Check if there is a VL6180x that is fresh out of reset at the default address
+func (device Vl6180x) isResetAtDefaultAddress() bool {
+	if IsVL6180x(device.Bus, defaultVl6180xAddress) != nil {
+		return false
+	}
+
+	freshOutOfReset, err := i2c.Device(device.Bus, defaultVl6180xAddress).ReadByteRegister(registerSystemFreshOutOfReset)
+	return err == nil && freshOutOfReset != 0
+}
+
+// IsVL6180x return nil if the device at a given I2C bus address is a VL6180x, identified by its model ID
+func IsVL6180x(bus i2c.Bus, address byte) error {
 	var value byte
 	var err error
 
-	if value, err = bus.Device(address).ReadByteRegister(registerSystemFreshOutOfReset); err != nil {
+	if value, err = i2c.Device(bus, address).ReadByteRegister(registerIdentificationModelID); err != nil {
 		return err
-	} else if value != 1 {
-		return i2c.I2CdeviceRegisterError{I2CdeviceError: i2c.I2CdeviceError{Address: address, Description: fmt.Sprintf("Expected 1 got %x", value)}, Register: registerSystemFreshOutOfReset}
+	} else if value != vl6180xModelID {
+		return i2c.I2CdeviceRegisterError{I2CdeviceError: i2c.I2CdeviceError{Address: address, Description: fmt.Sprintf("Expected model ID %#x got %#x", vl6180xModelID, value)}, Register: registerIdentificationModelID}
 	}
 
 	return nil
 }
 
+const (
+	vl6180xModelID       = 0xb4
+	vl6180xModelRevMajor = 0x01
+)
+
+const probeDriver = "vl6180x"
+
+// The probe writes a 16 bit register address, so it is run only at the addresses sensors are known to have: the
+// default address, and the addresses added by AddProbeAddresses
+func init() {
+	i2c.RegisterProbe(i2c.Prober{Driver: probeDriver, Addresses: []byte{defaultVl6180xAddress}, Probe: probe})
+}
+
+// AddProbeAddresses - let i2c.Identify look for sensors at given addresses, in addition to the default address.
+// Use with the addresses given to the sensors by SetAddress or AssignAddresses
+func AddProbeAddresses(addresses ...byte) {
+	i2c.AddProbeAddresses(probeDriver, addresses...)
+}
+
+// Identify VL6180x by its model ID register. The probe writes the 16 bit register address 0x0000, other devices
+// with 8 bit register addresses would take the second byte as a write of 0 to register 0, so the probe is limited
+// to VL6180x addresses
+func probe(device i2c.I2Cdevice) (i2c.Confidence, error) {
+	identification := make([]byte, 2)
+
+	if err := device.ReadRegisters(registerIdentificationModelID, identification); err != nil {
+		return i2c.ConfidenceNone, err
+	}
+
+	switch {
+	case identification[0] != vl6180xModelID:
+		return i2c.ConfidenceNone, nil
+	case identification[1] != vl6180xModelRevMajor:
+		return i2c.ConfidenceMedium, nil
+	}
+
+	return i2c.ConfidenceHigh, nil
+}
+
 type registerSettingsTable []struct {
 	register uint16
 	value    byte
 }
 
 func (device Vl6180x) setRegisters(settingTable registerSettingsTable) error {
+	batch := device.Batch()
+
 	for _, entry := range settingTable {
-		if err := device.WriteByteRegister(entry.register, entry.value); err != nil {
-			return err
-		}
+		batch.WriteByteRegister(entry.register, entry.value)
 	}
 
-	return nil
+	_, err := batch.Flush()
+	return err
 }
 
-// Initialize - initialize device for proper operation.
+// Initialize - initialize device for proper operation. SYSTEM__FRESH_OUT_OF_RESET is cleared when done, so a later
+// reset of the sensor can be detected (see Resync)
 func (device Vl6180x) Initialize() error {
 	if err := IsVL6180x(device.Bus, device.Address); err != nil {
 		return err
@@ -203,7 +444,8 @@ func (device Vl6180x) Initialize() error {
 		return err
 	}
 
-	return nil
+	// The sensor is configured, a set flag now means it was reset (see Resync)
+	return device.WriteByteRegister(registerSystemFreshOutOfReset, 0)
 }
 
 // GetIdentification - get device information
@@ -242,12 +484,68 @@ func (device Vl6180x) GetIdentification() (*Vl6180identification, error) {
 	return &result, nil
 }
 
-// SetAddress - change the device address on the bus
+// GetRangeStatistics - get the ranging counters of the last range measurement
+func (device Vl6180x) GetRangeStatistics() (*RangeStatistics, error) {
+	buffer := make([]byte, registerResultRangeReferenceConvTime+4-registerResultRangeReturnSignalCount)
+
+	if err := device.ReadRegisters(registerResultRangeReturnSignalCount, buffer); err != nil {
+		return nil, err
+	}
+
+	counter := func(register uint16) uint32 {
+		return binary.BigEndian.Uint32(buffer[register-registerResultRangeReturnSignalCount:])
+	}
+
+	return &RangeStatistics{
+		ReturnSignalCount:        counter(registerResultRangeReturnSignalCount),
+		ReferenceSignalCount:     counter(registerResultRangeReferenceSignalCount),
+		ReturnAmbientCount:       counter(registerResultRangeReturnAmbCount),
+		ReferenceAmbientCount:    counter(registerResultRangeReferenceAmbCount),
+		ReturnConvergenceTime:    counter(registerResultRangeReturnConvTime),
+		ReferenceConvergenceTime: counter(registerResultRangeReferenceConvTime),
+	}, nil
+}
+
+// GetHistory - get the content of the eight history buffers
+func (device Vl6180x) GetHistory() ([8]uint16, error) {
+	var history [8]uint16
+	buffer := make([]byte, 2*len(history))
+
+	if err := device.ReadRegisters(registerResultHistoryBuffer0, buffer); err != nil {
+		return history, err
+	}
+
+	for i := range history {
+		history[i] = binary.BigEndian.Uint16(buffer[2*i:])
+	}
+
+	return history, nil
+}
+
+// Tx - run fn with exclusive access to the device's bus, for sequences of register accesses that must not
+// be interleaved with bus operations of other goroutines
+func (device Vl6180x) Tx(fn func(device Vl6180x) error) error {
+	return device.I2Cdevice.Tx(func(device i2c.I2Cdevice) error {
+		return fn(Vl6180x{device})
+	})
+}
+
+// SetAddress - change the device address on the bus. If writes are verified (see WithVerify), the sensor is
+// read at the new address to check that it moved
 func (device *Vl6180x) SetAddress(newAddress byte) error {
 	if err := device.WriteByteRegister(registerI2CSlaveDeviceAddress, newAddress); err != nil {
 		return err
 	}
 
+	if device.Verify != nil {
+		moved := device.I2Cdevice
+		moved.Address = newAddress
+
+		if err := moved.VerifyRegisters(registerI2CSlaveDeviceAddress, []byte{newAddress}); err != nil {
+			return err
+		}
+	}
+
 	device.Address = newAddress
 	return nil
 }
@@ -260,6 +558,12 @@ func (device *Vl6180x) SetAddress(newAddress byte) error {
 // factor increases the sensor's potential maximum range but reduces its
 // resolution.
 func (device Vl6180x) SetScaling(scale byte) error {
+	return device.Tx(func(device Vl6180x) error {
+		return device.setScaling(scale)
+	})
+}
+
+func (device Vl6180x) setScaling(scale byte) error {
 	var err error
 	var partToPartRangeOffset byte
 	const defaultCrosstalkValidHeight = 20
@@ -286,41 +590,53 @@ func (device Vl6180x) SetScaling(scale byte) error {
 		return err
 	}
 
-	if earlyConvergenceEnabled, err := device.ReadByteRegister(registerSysrangeRangeCheckEnables); err == nil {
-		var mask byte
-
-		if scale == 1 {
-			mask = 1
-		}
-
-		if err = device.WriteByteRegister(registerSysrangeRangeCheckEnables, (earlyConvergenceEnabled&0xf)|mask); err != nil {
-			return err
-		}
-	} else {
-		return err
+	// Early convergence estimate is valid only without scaling, enable it for 1x scaling. The upper nibble
+	// of the register is cleared
+	mask, value := uint32(0xf0), uint32(0)
+	if scale == 1 {
+		mask |= earlyConvergenceEnable.Mask()
+		value = earlyConvergenceEnable.Value(1)
 	}
 
-	return nil
+	return device.UpdateBits(sysrangeRangeCheckEnables, mask, value)
 }
 
 // ReadRange - Performs a single-shot ranging measurement
-//  if timeout != 0, wait upto timeout millseconds for reading
+//
+//	if timeout != 0, wait upto timeout millseconds for reading
 func (device Vl6180x) ReadRange(timeout int) (byte, error) {
-	if err := device.WriteByteRegister(registerSysrangeStart, 0x01); err != nil {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+
+	return device.ReadRangeContext(ctx)
+}
+
+// ReadRangeContext - Performs a single-shot ranging measurement, waiting for the reading until ctx is done
+func (device Vl6180x) ReadRangeContext(ctx context.Context) (byte, error) {
+	if err := device.WriteByteRegisterContext(ctx, registerSysrangeStart, 0x01); err != nil {
 		return 0xff, err
 	}
 
-	return device.ReadRangeContinous(timeout)
+	return device.ReadRangeContinousContext(ctx)
 }
 
 // ReadAmbient - Performs a single-shot ambient measurement
-//  if timeout != 0, wait upto timeout millseconds for reading
+//
+//	if timeout != 0, wait upto timeout millseconds for reading
 func (device Vl6180x) ReadAmbient(timeout int) (uint16, error) {
-	if err := device.WriteByteRegister(registerSysalsStart, 0x01); err != nil {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+
+	return device.ReadAmbientContext(ctx)
+}
+
+// ReadAmbientContext - Performs a single-shot ambient measurement, waiting for the reading until ctx is done
+func (device Vl6180x) ReadAmbientContext(ctx context.Context) (uint16, error) {
+	if err := device.WriteByteRegisterContext(ctx, registerSysalsStart, 0x01); err != nil {
 		return 0, err
 	}
 
-	return device.ReadAmbientContinous(timeout)
+	return device.ReadAmbientContinousContext(ctx)
 }
 
 // VStartRangeContinuous - Starts continuous ranging measurements with the given period in ms
@@ -432,23 +748,24 @@ func (device Vl6180x) IsRangeReadingAvailable() (bool, error) {
 
 // PeekRange - check if range reading is available. If it is, read it
 // The function returns three values:
-//  err - not nil in case of error
-//  valueAvailable - true if range reading was available, false if reading is not yet available
-//  value - valid if valueAvailable is true
+//
+//	err - not nil in case of error
+//	valueAvailable - true if range reading was available, false if reading is not yet available
+//	value - valid if valueAvailable is true
 func (device Vl6180x) PeekRange() (valueAvailable bool, value byte, err error) {
-	if valueAvailable, err = device.IsRangeReadingAvailable(); err != nil {
-		return
-	}
+	err = device.Tx(func(device Vl6180x) error {
+		var err error
 
-	if valueAvailable {
-		if value, err = device.ReadByteRegister(registerResultRangeVal); err != nil {
-			return
+		if valueAvailable, err = device.IsRangeReadingAvailable(); err != nil || !valueAvailable {
+			return err
 		}
 
-		if err = device.WriteByteRegister(registerSystemInterruptClear, 0x01); err != nil {
-			return
+		if value, err = device.ReadByteRegister(registerResultRangeVal); err != nil {
+			return err
 		}
-	}
+
+		return device.WriteByteRegister(registerSystemInterruptClear, 0x01)
+	})
 
 	return
 }
@@ -456,18 +773,28 @@ func (device Vl6180x) PeekRange() (valueAvailable bool, value byte, err error) {
 // ReadRangeContinous - Returns a range reading when continuous mode is activated
 // (readRangeSingle() also calls this function after starting a single-shot
 // range measurement)
-//  if timeout != 0, wait upto timeout millseconds for reading
+//
+//	if timeout != 0, wait upto timeout millseconds for reading
 func (device Vl6180x) ReadRangeContinous(timeout int) (byte, error) {
-	var milliStart int
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
 
-	if timeout != 0 {
-		milliStart = time.Now().Nanosecond() / 1000000
-	}
+	return device.ReadRangeContinousContext(ctx)
+}
+
+// ReadRangeContinousContext - Returns a range reading when continuous mode is activated, waiting
+// for the reading until ctx is done
+func (device Vl6180x) ReadRangeContinousContext(ctx context.Context) (byte, error) {
+	sensor := Vl6180x{device.WithContext(ctx)}
 
 	for {
-		valueAvailable, value, err := device.PeekRange()
+		valueAvailable, value, err := sensor.PeekRange()
 
 		if err != nil {
+			if ctx.Err() != nil {
+				return 0xff, device.contextError(ctx, "ReadRange")
+			}
+
 			return 0xff, err
 		}
 
@@ -475,8 +802,8 @@ func (device Vl6180x) ReadRangeContinous(timeout int) (byte, error) {
 			return value, nil
 		}
 
-		if timeout != 0 && time.Now().Nanosecond()/1000000-milliStart > timeout {
-			return 0xff, Timeout{i2c.I2CdeviceError{Address: device.Address, Description: "ReadRange timeout"}}
+		if ctx.Err() != nil {
+			return 0xff, device.contextError(ctx, "ReadRange")
 		}
 	}
 }
@@ -496,23 +823,24 @@ func (device Vl6180x) IsAmbientReadingAvailable() (bool, error) {
 
 // PeekAmbient - check if ambient reading is available. If it is, read it
 // The function returns three values:
-//  err - not nil in case of error
-//  valueAvailable - true if ambient reading was available, false if reading is not yet available
-//  value - valid if valueAvailable is true
+//
+//	err - not nil in case of error
+//	valueAvailable - true if ambient reading was available, false if reading is not yet available
+//	value - valid if valueAvailable is true
 func (device Vl6180x) PeekAmbient() (valueAvailable bool, value uint16, err error) {
-	if valueAvailable, err = device.IsAmbientReadingAvailable(); err != nil {
-		return
-	}
+	err = device.Tx(func(device Vl6180x) error {
+		var err error
 
-	if valueAvailable {
-		if value, err = device.ReadWordRegister(registerResultAlsVal); err != nil {
-			return
+		if valueAvailable, err = device.IsAmbientReadingAvailable(); err != nil || !valueAvailable {
+			return err
 		}
 
-		if err = device.WriteByteRegister(registerSystemInterruptClear, 0x02); err != nil {
-			return
+		if value, err = device.ReadWordRegister(registerResultAlsVal); err != nil {
+			return err
 		}
-	}
+
+		return device.WriteByteRegister(registerSystemInterruptClear, 0x02)
+	})
 
 	return
 }
@@ -520,18 +848,28 @@ func (device Vl6180x) PeekAmbient() (valueAvailable bool, value uint16, err erro
 // ReadAmbientContinous - Returns an ambient light reading when continuous mode is activated
 // (readAmbientSingle() also calls this function after starting a single-shot
 // ambient light measurement)
-//  if timeout != 0, wait upto timeout millseconds for reading
+//
+//	if timeout != 0, wait upto timeout millseconds for reading
 func (device Vl6180x) ReadAmbientContinous(timeout int) (uint16, error) {
-	var milliStart int
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
 
-	if timeout != 0 {
-		milliStart = time.Now().Nanosecond() / 1000000
-	}
+	return device.ReadAmbientContinousContext(ctx)
+}
+
+// ReadAmbientContinousContext - Returns an ambient light reading when continuous mode is activated, waiting
+// for the reading until ctx is done
+func (device Vl6180x) ReadAmbientContinousContext(ctx context.Context) (uint16, error) {
+	sensor := Vl6180x{device.WithContext(ctx)}
 
 	for {
-		valueAvailable, value, err := device.PeekAmbient()
+		valueAvailable, value, err := sensor.PeekAmbient()
 
 		if err != nil {
+			if ctx.Err() != nil {
+				return 0xff, device.contextError(ctx, "ReadAmbient")
+			}
+
 			return 0, err
 		}
 
@@ -539,15 +877,33 @@ func (device Vl6180x) ReadAmbientContinous(timeout int) (uint16, error) {
 			return value, nil
 		}
 
-		if timeout != 0 && time.Now().Nanosecond()/1000000-milliStart > timeout {
-			return 0xff, Timeout{i2c.I2CdeviceError{Address: device.Address, Description: "ReadAmbient timeout"}}
+		if ctx.Err() != nil {
+			return 0xff, device.contextError(ctx, "ReadAmbient")
 		}
 	}
 }
 
+// Convert timeout in milliseconds (0 - no timeout) to context
+func timeoutContext(timeout int) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+}
+
+// Error returned when ctx is done while waiting for a reading
+func (device Vl6180x) contextError(ctx context.Context, operation string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return Timeout{i2c.I2CdeviceError{Address: device.Address, Description: operation + " timeout", Err: ctx.Err()}}
+	}
+
+	return i2c.I2CdeviceError{Address: device.Address, Description: operation + " cancelled", Err: ctx.Err()}
+}
+
 func (device Vl6180x) SetGPIO1low() {
-	device.WriteByteRegister(registerSystemModeGpio1, 0b00110000)
+	device.Set(systemModeGpio1, gpio1Select.Value(gpio1SelectInterrupt)|gpio1Polarity.Value(1)) // Active high interrupt output, no interrupt pending
 }
 func (device Vl6180x) SetGPIO1high() {
-	device.WriteByteRegister(registerSystemModeGpio1, 0b00000000)
+	device.Set(systemModeGpio1, gpio1Select.Value(gpio1SelectOff)) // Output off, the pin is pulled up
 }
@@ -18,12 +18,23 @@ type RangeValueMessage struct {
 	Distance byte
 }
 
-// ScanBus - return group of all VL6180x sensors found on the bus
+// ScanBus - return group of all VL6180x sensors found on the bus. Only addresses acknowledged when the bus is
+// scanned are checked for being a VL6180x. Addresses of EEPROMs and write only devices are not checked (see
+// i2c.IsWriteSensitiveAddress), since reading the model ID writes a register address. Addresses that fail to
+// be probed are skipped
 //
-func ScanBus(bus *i2c.I2Cbus) (Vl6180xGroup, error) {
+func ScanBus(bus i2c.Bus) (Vl6180xGroup, error) {
 	sensors := make([]Vl6180x, 0, 10)
 
-	for address := byte(0); address < 127; address++ {
+	for address := byte(i2c.FirstScanAddress); address <= i2c.LastScanAddress; address++ {
+		if i2c.IsWriteSensitiveAddress(address) {
+			continue
+		}
+
+		if present, err := i2c.Probe(bus, address, i2c.ProbeAuto); err != nil || !present {
+			continue
+		}
+
 		if IsVL6180x(bus, address) == nil {
 			sensors = append(sensors, Device(bus, address))
 		}
@@ -67,7 +78,18 @@ func ScanBus(bus *i2c.I2Cbus) (Vl6180xGroup, error) {
 //      resetStateOn - function that would place the first sensor in the chain in reset state
 //      reserStateOff - function that would take the first sensor in the chain out of reset state
 //
-func AssignAddresses(bus *i2c.I2Cbus, startAddress byte, resetStateOn func(), resetStateOff func()) (Vl6180xGroup, error) {
+func AssignAddresses(bus i2c.Bus, startAddress byte, resetStateOn func(), resetStateOff func()) (Vl6180xGroup, error) {
+	return AssignAddressesUsing(bus, startAddress, resetStateOn, resetStateOff, Device)
+}
+
+// AssignAddressesUsing - like AssignAddresses, but get the sensor objects using a given function. Use it to
+// initialize the sensors and change their address with write verification, caching or retries, for example:
+//
+//   vl6180x.AssignAddressesUsing(bus, 42, resetOn, resetOff, func(bus i2c.Bus, address byte) vl6180x.Vl6180x {
+//       return vl6180x.Device(bus, address).WithVerify()
+//   })
+//
+func AssignAddressesUsing(bus i2c.Bus, startAddress byte, resetStateOn func(), resetStateOff func(), device func(bus i2c.Bus, address byte) Vl6180x) (Vl6180xGroup, error) {
 	sensors := make(Vl6180xGroup, 0, 10)
 	address := startAddress
 	var sensor *Vl6180x = nil
@@ -98,12 +120,14 @@ func AssignAddresses(bus *i2c.I2Cbus, startAddress byte, resetStateOn func(), re
 		}
 
 		// Found sensor at the default address
-		nextSensor := Device(bus, defaultVl6180xAddress)
+		nextSensor := device(bus, defaultVl6180xAddress)
 		if err := nextSensor.Initialize(); err != nil {
 			return sensors, err
 		}
 
-		nextSensor.SetAddress(address)
+		if err := nextSensor.SetAddress(address); err != nil {
+			return sensors, err
+		}
 		address = address + 1
 
 		sensors = append(sensors, nextSensor)
@@ -113,6 +137,42 @@ func AssignAddresses(bus *i2c.I2Cbus, startAddress byte, resetStateOn func(), re
 	return sensors, nil
 }
 
+// WithRetryPolicy - get group of sensors that retry failed register access according to a policy
+func (sensors Vl6180xGroup) WithRetryPolicy(policy *i2c.RetryPolicy) Vl6180xGroup {
+	result := make(Vl6180xGroup, len(sensors))
+
+	for i, sensor := range sensors {
+		result[i] = sensor.WithRetryPolicy(policy)
+	}
+
+	return result
+}
+
+// Resync - resync the sensors in chain order (see Vl6180x.Resync), returns true if any sensor was reset. The
+// sensors must cache their configuration (see AssignAddressesUsing and Vl6180x.WithCache).
+//
+// When the chain is reset (for example after a brown-out), each sensor holds the next one in reset, and only the
+// first sensor comes back at the default address. Restoring the configuration of a reset sensor (which includes
+// its GPIO1 setting) takes the next sensor out of reset, which is then given time to boot before it is resynced
+func (sensors Vl6180xGroup) Resync() (bool, error) {
+	anyReset := false
+
+	for i := range sensors {
+		reset, err := sensors[i].Resync()
+		if err != nil {
+			return anyReset || reset, err
+		}
+
+		if reset && i+1 < len(sensors) {
+			time.Sleep(sensorBootTimeMs * time.Millisecond)
+		}
+
+		anyReset = anyReset || reset
+	}
+
+	return anyReset, nil
+}
+
 func (sensors Vl6180xGroup) Initialize() error {
 	for _, sensor := range sensors {
 		if err := sensor.Initialize(); err != nil {